@@ -0,0 +1,215 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+	ycmetadata "github.com/ydb-platform/ydb-go-yc-metadata"
+	metadatatrace "github.com/ydb-platform/ydb-go-yc-metadata/trace"
+
+	yc "github.com/ydb-platform/ydb-go-yc"
+	"github.com/ydb-platform/ydb-go-yc/trace"
+)
+
+// credentialsFlags are the flags which configure credentials, shared by the commands.
+type credentialsFlags struct {
+	config       string
+	saKeyFile    string
+	metadata     bool
+	metadataURL  string
+	accessToken  string
+	iamEndpoint  string
+	installation string
+	caFile       string
+	oauthToken   string
+	ycProfile    string
+	ycConfig     string
+}
+
+func (f *credentialsFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.config, "config", "", "credentials config file (yc.Config in JSON or YAML)")
+	fs.StringVar(&f.saKeyFile, "sa-key-file", "", "service account key file")
+	fs.BoolVar(&f.metadata, "metadata", false, "use metadata credentials")
+	fs.StringVar(&f.metadataURL, "metadata-url", "", "metadata token url, implies -metadata")
+	fs.StringVar(&f.accessToken, "access-token", "", "static access token")
+	fs.StringVar(&f.iamEndpoint, "iam-endpoint", "", "iam endpoint for service account key credentials")
+	fs.StringVar(&f.installation, "installation", "", "Yandex Cloud installation name, e.g. yandex-cloud-kz")
+	fs.StringVar(&f.caFile, "ca-file", "", "additional CA certificates of the iam endpoint")
+	fs.StringVar(&f.oauthToken, "oauth-token", "", "OAuth token of Yandex account")
+	fs.StringVar(&f.ycProfile, "yc-profile", "", "profile of yc CLI, 'current' for the active profile")
+	fs.StringVar(&f.ycConfig, "yc-config", defaultYCConfig(), "config of yc CLI")
+}
+
+// Config returns the credentials config from the flags, the config file or the environment.
+func (f *credentialsFlags) Config() (*yc.Config, error) {
+	return f.configFrom(os.LookupEnv)
+}
+
+// configFrom is Config with the environment variables looked up by lookup.
+func (f *credentialsFlags) configFrom(lookup func(string) (string, bool)) (*yc.Config, error) {
+	if f.config != "" {
+		return loadConfig(f.config)
+	}
+	c := &yc.Config{
+		Endpoint:     f.iamEndpoint,
+		Installation: f.installation,
+		CAFile:       f.caFile,
+	}
+	switch {
+	case f.saKeyFile != "":
+		c.Source, c.ServiceAccountKeyFile = yc.SourceServiceAccountKeyFile, f.saKeyFile
+	case f.metadata || f.metadataURL != "":
+		c.Source, c.MetadataURL = yc.SourceMetadata, f.metadataURL
+	case f.accessToken != "":
+		c.Source, c.AccessToken = yc.SourceAccessToken, f.accessToken
+	default:
+		env, err := yc.ConfigFromEnviron(lookup)
+		if err != nil {
+			return nil, err
+		}
+		// Only service account keys talk to the iam endpoint, other sources reject its settings.
+		if env.Source == yc.SourceServiceAccountKeyFile || env.Source == yc.SourceServiceAccountKey {
+			if c.Endpoint != "" {
+				env.Endpoint = c.Endpoint
+			}
+			env.Installation, env.CAFile = c.Installation, c.CAFile
+		}
+		c = env
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Credentials returns credentials and the tracker of expiration of their tokens. Service account key,
+// OAuth and metadata credentials are created with yc.NewClient and yc.NewInstanceServiceAccount,
+// other sources with yc.Config.
+func (f *credentialsFlags) Credentials() (credentials.Credentials, *expiry, error) {
+	e := &expiry{}
+	connection := &yc.Config{
+		Endpoint:     f.iamEndpoint,
+		Installation: f.installation,
+		CAFile:       f.caFile,
+	}
+	switch {
+	case f.oauthToken != "":
+		creds, err := yc.NewClient(append(connection.ClientOptions(),
+			yc.WithOAuthToken(f.oauthToken), yc.WithTrace(e.trace()), yc.WithSourceInfo("-oauth-token"),
+		)...)
+
+		return creds, e, err
+	case f.ycProfile != "":
+		opts, metadata, err := profileOptions(f.ycConfig, f.ycProfile)
+		if err != nil {
+			return nil, nil, err
+		}
+		if metadata {
+			return yc.NewInstanceServiceAccount(ycmetadata.WithTrace(e.metadataTrace())), e, nil
+		}
+		creds, err := yc.NewClient(append(append(connection.ClientOptions(), opts...),
+			yc.WithTrace(e.trace()), yc.WithSourceInfo("yc CLI profile "+f.ycProfile),
+		)...)
+
+		return creds, e, err
+	}
+
+	c, err := f.Config()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case len(c.Fallback) > 0:
+		creds, err := c.Credentials()
+
+		return creds, e, err
+	case c.Source == yc.SourceServiceAccountKeyFile || c.Source == yc.SourceServiceAccountKey:
+		creds, err := yc.NewClient(append(c.ClientOptions(),
+			yc.WithTrace(e.trace()), yc.WithSourceInfo(c.Source),
+		)...)
+
+		return creds, e, err
+	case c.Source == yc.SourceMetadata:
+		opts := []ycmetadata.InstanceServiceAccountCredentialsOption{ycmetadata.WithTrace(e.metadataTrace())}
+		if c.MetadataURL != "" {
+			opts = append(opts, ycmetadata.WithURL(c.MetadataURL))
+		}
+
+		return yc.NewInstanceServiceAccount(opts...), e, nil
+	default:
+		creds, err := c.Credentials()
+
+		return creds, e, err
+	}
+}
+
+// unknownExpiryWindow is the refresh window of credentials which report no expiration: static tokens,
+// which are never refreshed, and fallback chains, which are not traced. The token may be replaced or
+// expire at any moment, so clients of serve-metadata are asked to re-request it soon. Credentials cache
+// their tokens, so frequent requests do not reach the iam service.
+const unknownExpiryWindow = time.Minute
+
+// expiry tracks expiration of the last token received by credentials through their traces.
+type expiry struct {
+	mu sync.Mutex
+	at time.Time
+}
+
+func (e *expiry) set(at time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.at = at
+}
+
+// ExpiresAt returns expiration of the last token, zero if unknown.
+func (e *expiry) ExpiresAt() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.at
+}
+
+func (e *expiry) trace() trace.Trace {
+	return trace.Trace{
+		OnTokenRefreshed: func(info trace.TokenRefreshedInfo) {
+			if info.Error == nil {
+				e.set(info.ExpiresAt)
+			}
+		},
+	}
+}
+
+func (e *expiry) metadataTrace() metadatatrace.Trace {
+	return metadatatrace.Trace{
+		OnRefreshToken: func(metadatatrace.RefreshTokenStartInfo) func(metadatatrace.RefreshTokenDoneInfo) {
+			start := time.Now()
+
+			return func(info metadatatrace.RefreshTokenDoneInfo) {
+				if info.Error == nil {
+					e.set(start.Add(info.ExpiresIn))
+				}
+			}
+		},
+	}
+}
+
+// loadConfig reads yc.Config from JSON or, by the file extension, YAML file.
+func loadConfig(path string) (*yc.Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yc.ParseConfigYAML(data)
+	default:
+		return yc.ParseConfig(data)
+	}
+}
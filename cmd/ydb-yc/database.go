@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ydb-platform/ydb-go-sdk/v3"
+	"github.com/ydb-platform/ydb-go-sdk/v3/table"
+
+	yc "github.com/ydb-platform/ydb-go-yc"
+)
+
+const envConnectionString = "YDB_CONNECTION_STRING"
+
+// databaseFlags configure connection to the database.
+type databaseFlags struct {
+	credentialsFlags
+
+	database string
+	timeout  time.Duration
+}
+
+func (f *databaseFlags) register(fs *flag.FlagSet) {
+	f.credentialsFlags.register(fs)
+	fs.StringVar(&f.database, "database", os.Getenv(envConnectionString),
+		"database connection string, "+envConnectionString+" by default",
+	)
+	fs.DurationVar(&f.timeout, "timeout", 30*time.Second, "timeout of the command including connection")
+}
+
+// open connects to the database with the configured credentials. The commands bound ctx by
+// -timeout, so requests after the connection are bounded too.
+func (f *databaseFlags) open(ctx context.Context) (*ydb.Driver, string, error) {
+	if f.database == "" {
+		return nil, "", errors.New("database connection string is required (-database or " + envConnectionString + ")")
+	}
+	creds, _, err := f.Credentials()
+	if err != nil {
+		return nil, "", err
+	}
+	db, err := ydb.Open(ctx, f.database,
+		yc.WithInternalCA(),
+		ydb.WithCredentials(creds),
+		ydb.WithDialTimeout(f.timeout),
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot connect to '%s': %w", f.database, err)
+	}
+
+	return db, fmt.Sprint(creds), nil
+}
+
+func runWhoAmI(ctx context.Context, args []string, stdout io.Writer) error {
+	var (
+		fs = flag.NewFlagSet("whoami", flag.ContinueOnError)
+		db databaseFlags
+	)
+	db.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	driver, creds, err := db.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = driver.Close(ctx)
+	}()
+	whoAmI, err := driver.Discovery().WhoAmI(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "credentials: %s\n", creds)
+	fmt.Fprintf(stdout, "user:        %s\n", whoAmI.User)
+	for _, group := range whoAmI.Groups {
+		fmt.Fprintf(stdout, "group:       %s\n", group)
+	}
+
+	return nil
+}
+
+func runPing(ctx context.Context, args []string, stdout io.Writer) error {
+	var (
+		fs = flag.NewFlagSet("ping", flag.ContinueOnError)
+		db databaseFlags
+	)
+	db.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, db.timeout)
+	defer cancel()
+
+	start := time.Now()
+	driver, _, err := db.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = driver.Close(ctx)
+	}()
+	connected := time.Since(start)
+	err = driver.Table().Do(ctx, func(ctx context.Context, s table.Session) error {
+		return s.KeepAlive(ctx)
+	}, table.WithIdempotent())
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "ok: connected in %v, session in %v\n",
+		connected.Round(time.Millisecond), (time.Since(start) - connected).Round(time.Millisecond),
+	)
+
+	return err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func runDecodeJWT(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("decode-jwt", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ydb-yc decode-jwt [jwt]")
+		fmt.Fprintln(fs.Output(), "The jwt is read from stdin if not provided.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	token := fs.Arg(0)
+	if token == "" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		token = line
+	}
+
+	return decodeJWT(strings.TrimSpace(token), stdout)
+}
+
+// decodeJWT prints the header and the claims of the token, time claims are annotated with dates.
+func decodeJWT(token string, w io.Writer) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("jwt must have 3 parts, got %d", len(parts))
+	}
+	var decoded [2]map[string]interface{}
+	for i, name := range []string{"header", "claims"} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			return fmt.Errorf("cannot decode %s: %w", name, err)
+		}
+		if err = json.Unmarshal(data, &decoded[i]); err != nil {
+			return fmt.Errorf("cannot parse %s: %w", name, err)
+		}
+	}
+	for _, claim := range []string{"iat", "exp", "nbf"} {
+		if v, ok := decoded[1][claim].(float64); ok {
+			decoded[1][claim+"_time"] = time.Unix(int64(v), 0).UTC().Format(time.RFC3339)
+		}
+	}
+
+	out := json.NewEncoder(w)
+	out.SetIndent("", "  ")
+
+	return out.Encode(struct {
+		Header map[string]interface{} `json:"header"`
+		Claims map[string]interface{} `json:"claims"`
+	}{decoded[0], decoded[1]})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	yc "github.com/ydb-platform/ydb-go-yc"
+)
+
+func runValidateKey(ctx context.Context, args []string, stdout io.Writer) error {
+	var (
+		fs         = flag.NewFlagSet("validate-key", flag.ContinueOnError)
+		connection credentialsFlags
+		exchange   = fs.Bool("exchange", false, "exchange the key for an iam token to check it is active")
+	)
+	fs.StringVar(&connection.iamEndpoint, "iam-endpoint", "", "iam endpoint for -exchange")
+	fs.StringVar(&connection.installation, "installation", "", "Yandex Cloud installation name for -exchange")
+	fs.StringVar(&connection.caFile, "ca-file", "", "additional CA certificates of the iam endpoint")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ydb-yc validate-key [flags] <key-file>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+
+		return errors.New("key file is required")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	info, err := yc.InspectServiceAccountKey(data)
+	printKeyInfo(stdout, info)
+	if err != nil {
+		return err
+	}
+	if info.HasPublicKey && !info.PublicKeyMatches {
+		return errors.New("public_key does not match private_key")
+	}
+	if !*exchange {
+		_, err = fmt.Fprintln(stdout, "key is valid")
+
+		return err
+	}
+
+	e := &expiry{}
+	connection.saKeyFile = fs.Arg(0)
+	c, err := connection.Config()
+	if err != nil {
+		return err
+	}
+	creds, err := yc.NewClient(append(c.ClientOptions(), yc.WithTrace(e.trace()))...)
+	if err != nil {
+		return err
+	}
+	if _, err = creds.Token(ctx); err != nil {
+		return fmt.Errorf("exchange failed: %w", err)
+	}
+	_, err = fmt.Fprintf(stdout, "exchange:     ok, token expires at %s\n", e.ExpiresAt().UTC().Format(time.RFC3339))
+
+	return err
+}
+
+func printKeyInfo(w io.Writer, info *yc.ServiceAccountKeyInfo) {
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%-13s %s\n", name+":", value)
+		}
+	}
+	field("key id", info.KeyID)
+	field("account id", info.ServiceAccountID)
+	field("endpoint", info.Endpoint)
+	field("algorithm", info.KeyAlgorithm)
+	field("created at", info.CreatedAt)
+	if info.KeyType != "" {
+		field("key", fmt.Sprintf("%s %d bits, %s in PEM block '%s'", info.KeyType, info.KeySize, info.Encoding, info.PEMType))
+	}
+	switch {
+	case !info.HasPublicKey:
+		field("public key", "absent")
+	case info.PublicKeyMatches:
+		field("public key", "matches private key")
+	case info.KeyType != "":
+		field("public key", "DOES NOT match private key")
+	}
+}
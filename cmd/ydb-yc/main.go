@@ -0,0 +1,86 @@
+// Command ydb-yc helps to debug authentication of YDB clients in Yandex Cloud with the same code
+// paths as ydb-go-yc uses in services.
+//
+// Usage:
+//
+//	ydb-yc <command> [flags]
+//
+// Commands:
+//
+//	token           print an iam token of the configured credentials
+//	whoami          print the credentials and the user seen by the database
+//	ping            connect to the database and create a session
+//	validate-key    check the service account key file
+//	decode-jwt      print the header and claims of a jwt without verification
+//	serve-metadata  serve tokens on a metadata-compatible http endpoint
+//
+// Credentials are configured with flags (see `ydb-yc <command> -h`), a config file (-config) or,
+// if neither is provided, environment variables recognized by yc.WithEnvironCredentials.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, args []string, stdout io.Writer) error
+}
+
+var commands = map[string]command{
+	"token":          {"print an iam token of the configured credentials", runToken},
+	"whoami":         {"print the credentials and the user seen by the database", runWhoAmI},
+	"ping":           {"connect to the database and create a session", runPing},
+	"validate-key":   {"check the service account key file", runValidateKey},
+	"decode-jwt":     {"print the header and claims of a jwt without verification", runDecodeJWT},
+	"serve-metadata": {"serve tokens on a metadata-compatible http endpoint", runServeMetadata},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: ydb-yc <command> [flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-15s %s\n", name, commands[name].usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	if name := os.Args[1]; name == "-h" || name == "-help" || name == "help" {
+		usage(os.Stdout)
+
+		return
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ydb-yc: unknown command '%s'\n\n", os.Args[1])
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := cmd.run(ctx, os.Args[2:], os.Stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "ydb-yc %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
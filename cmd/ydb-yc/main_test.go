@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	yc "github.com/ydb-platform/ydb-go-yc"
+	"github.com/ydb-platform/ydb-go-yc/ydbyctest"
+)
+
+func TestEnvironConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		env      map[string]string
+		endpoint string // -iam-endpoint flag
+		install  string // -installation flag
+		caFile   string // -ca-file flag
+		source   string
+		want     string // endpoint of the config
+		err      bool
+	}{
+		{name: "Default", source: yc.SourceMetadata},
+		{
+			name:   "KeyFileWins",
+			env:    map[string]string{yc.EnvServiceAccountKeyFileCredentials: "sa.json", yc.EnvMetadataCredentials: "1"},
+			source: yc.SourceServiceAccountKeyFile,
+		},
+		{
+			name:   "EnvEndpoint",
+			env:    map[string]string{yc.EnvServiceAccountKeyFileCredentials: "sa.json", yc.EnvIAMEndpoint: "iam:443"},
+			source: yc.SourceServiceAccountKeyFile,
+			want:   "iam:443",
+		},
+		{
+			name:     "FlagEndpointWins",
+			env:      map[string]string{yc.EnvServiceAccountKeyFileCredentials: "sa.json", yc.EnvIAMEndpoint: "iam:443"},
+			endpoint: "flag:443",
+			source:   yc.SourceServiceAccountKeyFile,
+			want:     "flag:443",
+		},
+		{
+			name:   "MetadataIgnoresEnvEndpoint",
+			env:    map[string]string{yc.EnvMetadataCredentials: "1", yc.EnvIAMEndpoint: "iam:443"},
+			source: yc.SourceMetadata,
+		},
+		{
+			name:    "MetadataIgnoresIAMFlags",
+			env:     map[string]string{yc.EnvMetadataCredentials: "1"},
+			install: "yandex-cloud-kz",
+			caFile:  "ca.pem",
+			source:  yc.SourceMetadata,
+		},
+		{
+			name:     "AnonymousIgnoresIAMFlags",
+			env:      map[string]string{yc.EnvAnonymousCredentials: "1"},
+			endpoint: "flag:443",
+			caFile:   "ca.pem",
+			source:   yc.SourceAnonymous,
+		},
+		{name: "Anonymous", env: map[string]string{yc.EnvAnonymousCredentials: "1"}, source: yc.SourceAnonymous},
+		{name: "AccessToken", env: map[string]string{yc.EnvAccessTokenCredentials: "t"}, source: yc.SourceAccessToken},
+		{name: "NotBoolean", env: map[string]string{yc.EnvMetadataCredentials: "yes please"}, err: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f := credentialsFlags{iamEndpoint: tt.endpoint, installation: tt.install, caFile: tt.caFile}
+			c, err := f.configFrom(func(name string) (string, bool) {
+				v, ok := tt.env[name]
+
+				return v, ok
+			})
+			if tt.err {
+				require.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.source, c.Source)
+			require.Equal(t, tt.want, c.Endpoint)
+		})
+	}
+}
+
+func TestToken(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, runToken(context.Background(), []string{"-access-token", "secret"}, &out))
+	require.Equal(t, "secret\n", out.String())
+}
+
+func TestServeMetadata(t *testing.T) {
+	var creds credentialsFlags
+	creds.accessToken = "secret"
+	c, e, err := creds.Credentials()
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(metadataHandler(c, e))
+	defer srv.Close()
+
+	token, err := yc.NewInstanceServiceAccountURL(srv.URL + metadataTokenPath).Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "secret", token)
+
+	get := func(url string, header http.Header) (int, string) {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+		require.NoError(t, err)
+		req.Header = header
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() {
+			_ = res.Body.Close()
+		}()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		return res.StatusCode, string(body)
+	}
+	flavor := http.Header{"Metadata-Flavor": {"Google"}}
+
+	code, body := get(srv.URL+metadataTokenPath, flavor)
+	require.Equal(t, http.StatusOK, code)
+	// Expiration of static tokens is unknown.
+	require.Contains(t, body, `"expires_in":60`)
+
+	code, _ = get(srv.URL+metadataTokenPath, nil)
+	require.Equal(t, http.StatusForbidden, code)
+
+	code, _ = get(srv.URL+"/computeMetadata/v1/instance/hostname", flavor)
+	require.Equal(t, http.StatusNotFound, code)
+
+	failing := httptest.NewServer(metadataHandler(failingCredentials{}, &expiry{}))
+	defer failing.Close()
+	code, body = get(failing.URL+metadataTokenPath, flavor)
+	require.Equal(t, http.StatusInternalServerError, code)
+	require.NotContains(t, body, "sa.json")
+}
+
+// failingCredentials fail with an error revealing details of the configuration.
+type failingCredentials struct{}
+
+func (failingCredentials) Token(context.Context) (string, error) {
+	return "", errors.New("cannot read /secrets/sa.json")
+}
+
+func TestTokenJSON(t *testing.T) {
+	iam := ydbyctest.NewIAMTokenService()
+	srv, err := ydbyctest.NewServer(iam.Register)
+	require.NoError(t, err)
+	defer func() {
+		_ = srv.Close()
+	}()
+	iam.AddOAuthToken("y0_oauth", "user-1")
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, srv.CertificatePEM(), 0o600))
+	ycConfig := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(ycConfig, []byte(`
+current: dev
+profiles:
+  dev:
+    token: y0_oauth
+`), 0o600))
+
+	var out bytes.Buffer
+	require.NoError(t, runToken(context.Background(), []string{
+		"-format", "json", "-yc-config", ycConfig, "-yc-profile", "current",
+		"-iam-endpoint", srv.Endpoint, "-ca-file", caFile,
+	}, &out))
+	var res struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &res))
+	id, ok := iam.ServiceAccountID(res.Token)
+	require.True(t, ok)
+	require.Equal(t, "user-1", id)
+	require.WithinDuration(t, time.Now().Add(ydbyctest.DefaultTokenLifetime), res.ExpiresAt, time.Minute)
+
+	err = runToken(context.Background(), []string{"-yc-config", ycConfig, "-yc-profile", "prod"}, &out)
+	require.Error(t, err)
+}
+
+func TestValidateKey(t *testing.T) {
+	iam := ydbyctest.NewIAMTokenService()
+	srv, err := ydbyctest.NewServer(iam.Register)
+	require.NoError(t, err)
+	defer func() {
+		_ = srv.Close()
+	}()
+	key, err := iam.NewServiceAccountKey("sa-1")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "sa.json")
+	require.NoError(t, os.WriteFile(keyFile, []byte(key.JSON()), 0o600))
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, srv.CertificatePEM(), 0o600))
+
+	var out bytes.Buffer
+	require.NoError(t, runValidateKey(context.Background(), []string{
+		"-exchange", "-iam-endpoint", srv.Endpoint, "-ca-file", caFile, keyFile,
+	}, &out))
+	require.Contains(t, out.String(), "RSA 2048 bits, PKCS#8 in PEM block 'PRIVATE KEY'")
+	require.Contains(t, out.String(), "public key:   matches private key")
+	require.Contains(t, out.String(), "exchange:     ok")
+	require.NotContains(t, out.String(), "BEGIN PRIVATE KEY")
+
+	require.NoError(t, os.WriteFile(keyFile, []byte(`{"id":"key"}`), 0o600))
+	err = runValidateKey(context.Background(), []string{keyFile}, &out)
+	require.ErrorIs(t, err, yc.ErrServiceFileInvalid)
+}
+
+func TestDecodeJWT(t *testing.T) {
+	enc := base64.RawURLEncoding.EncodeToString
+	token := enc([]byte(`{"alg":"PS256","kid":"key"}`)) + "." + enc([]byte(`{"iss":"sa","exp":1704103200}`)) + ".sig"
+
+	var out bytes.Buffer
+	require.NoError(t, decodeJWT(token, &out))
+	require.Contains(t, out.String(), `"kid": "key"`)
+	require.Contains(t, out.String(), `"exp_time": "2024-01-01T10:00:00Z"`)
+
+	require.Error(t, decodeJWT("not a jwt", &out))
+}
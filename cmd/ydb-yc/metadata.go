@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+)
+
+// metadataTokenPath is the path of the token of the default service account in the metadata service.
+const metadataTokenPath = "/computeMetadata/v1/instance/service-accounts/default/token"
+
+func runServeMetadata(ctx context.Context, args []string, stdout io.Writer) error {
+	var (
+		fs     = flag.NewFlagSet("serve-metadata", flag.ContinueOnError)
+		creds  credentialsFlags
+		listen = fs.String("listen", "127.0.0.1:6770", "address to listen on, keep it on loopback")
+	)
+	creds.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	c, expiry, err := creds.Credentials()
+	if err != nil {
+		return err
+	}
+	ln, err := (&net.ListenConfig{}).Listen(ctx, "tcp", *listen)
+	if err != nil {
+		return err
+	}
+	if addr, ok := ln.Addr().(*net.TCPAddr); ok && !addr.IP.IsLoopback() {
+		log.Printf("ydb-yc: WARNING: tokens are served on non-loopback address %s", addr)
+	}
+	srv := &http.Server{
+		Handler:           metadataHandler(c, expiry),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(stdout, "serving %s on http://%s%s\n", c, ln.Addr(), metadataTokenPath)
+	if err = srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// metadataHandler serves tokens of creds on metadataTokenPath as the metadata service of the instance
+// does. Errors of creds are logged, clients receive a generic error without details.
+func metadataHandler(creds credentials.Credentials, expiry *expiry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != metadataTokenPath {
+			http.NotFound(w, r)
+
+			return
+		}
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "Missing required header: Metadata-Flavor: Google", http.StatusForbidden)
+
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+		now := time.Now()
+		token, err := creds.Token(r.Context())
+		if err != nil {
+			log.Printf("ydb-yc: cannot get token of %s: %v", creds, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+			return
+		}
+		expiresIn := unknownExpiryWindow
+		if expiresAt := expiry.ExpiresAt(); !expiresAt.IsZero() {
+			expiresIn = expiresAt.Sub(now)
+		}
+
+		w.Header().Set("Metadata-Flavor", "Google")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(struct {
+			AccessToken string `json:"access_token"` //nolint:tagliatelle // metadata service format.
+			ExpiresIn   int64  `json:"expires_in"`   //nolint:tagliatelle // metadata service format.
+			TokenType   string `json:"token_type"`   //nolint:tagliatelle // metadata service format.
+		}{
+			AccessToken: token,
+			ExpiresIn:   int64(expiresIn / time.Second),
+			TokenType:   "Bearer",
+		})
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	yc "github.com/ydb-platform/ydb-go-yc"
+)
+
+// ycCLIConfig is the config of yc CLI with profiles created by `yc init`.
+type ycCLIConfig struct {
+	Current  string               `yaml:"current"`
+	Profiles map[string]ycProfile `yaml:"profiles"`
+}
+
+type ycProfile struct {
+	Token                  string                 `yaml:"token"`
+	ServiceAccountKey      map[string]interface{} `yaml:"service-account-key"`
+	InstanceServiceAccount bool                   `yaml:"instance-service-account"`
+	FederationID           string                 `yaml:"federation-id"`
+	Endpoint               string                 `yaml:"endpoint"`
+}
+
+func defaultYCConfig() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".config", "yandex-cloud", "config.yaml")
+}
+
+// profileOptions returns client options of the yc CLI profile name ("current" for the active one),
+// or metadata if the profile uses the service account of the instance.
+func profileOptions(path, name string) (opts []yc.ClientOption, metadata bool, _ error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot read yc CLI config: %w", err)
+	}
+	var config ycCLIConfig
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		return nil, false, fmt.Errorf("cannot parse yc CLI config '%s': %w", path, err)
+	}
+	if name == "current" {
+		name = config.Current
+	}
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return nil, false, fmt.Errorf("profile '%s' not found in '%s'", name, path)
+	}
+
+	if profile.Endpoint != "" {
+		opts = append(opts, yc.WithEndpointDiscovery(profile.Endpoint))
+	}
+	switch {
+	case profile.ServiceAccountKey != nil:
+		key, err := json.Marshal(profile.ServiceAccountKey)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid service account key of profile '%s': %w", name, err)
+		}
+
+		return append(opts, yc.WithServiceKey(string(key))), false, nil
+	case profile.Token != "":
+		return append(opts, yc.WithOAuthToken(profile.Token)), false, nil
+	case profile.InstanceServiceAccount:
+		return nil, true, nil
+	case profile.FederationID != "":
+		return nil, false, fmt.Errorf("federated profile '%s' is not supported, use `yc iam create-token`", name)
+	default:
+		return nil, false, errors.New("profile '" + name + "' has no credentials")
+	}
+}
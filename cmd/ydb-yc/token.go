@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+func runToken(ctx context.Context, args []string, stdout io.Writer) error {
+	var (
+		fs     = flag.NewFlagSet("token", flag.ContinueOnError)
+		creds  credentialsFlags
+		format = fs.String("format", "text", "output format: text (the token only) or json")
+	)
+	creds.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format '%s'", *format)
+	}
+
+	c, expiry, err := creds.Credentials()
+	if err != nil {
+		return err
+	}
+	token, err := c.Token(ctx)
+	if err != nil {
+		return err
+	}
+	if *format == "text" {
+		_, err = fmt.Fprintln(stdout, token)
+
+		return err
+	}
+
+	res := struct {
+		Token     string `json:"token"`
+		ExpiresAt string `json:"expires_at,omitempty"` //nolint:tagliatelle // credential helper format.
+	}{
+		Token: token,
+	}
+	if at := expiry.ExpiresAt(); !at.IsZero() {
+		res.ExpiresAt = at.UTC().Format(time.RFC3339)
+	}
+
+	return json.NewEncoder(stdout).Encode(res)
+}
@@ -0,0 +1,307 @@
+package yc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ydb-platform/ydb-go-sdk/v3"
+	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+	"gopkg.in/yaml.v3"
+)
+
+// Credentials sources of Config.
+const (
+	SourceServiceAccountKeyFile = "service_account_key_file"
+	SourceServiceAccountKey     = "service_account_key"
+	SourceMetadata              = "metadata"
+	SourceAccessToken           = "access_token"
+	SourceAnonymous             = "anonymous"
+)
+
+// Duration is time.Duration which is (un)marshaled as a string like "1h30m".
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+
+	return nil
+}
+
+// RetryConfig configures retries of token creation, see WithRetry.
+type RetryConfig struct {
+	Attempts int      `json:"attempts" yaml:"attempts"`
+	Backoff  Duration `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+}
+
+// CacheConfig configures caching of tokens, see WithRefreshRatio.
+type CacheConfig struct {
+	RefreshRatio float64 `json:"refresh_ratio" yaml:"refresh_ratio"`
+}
+
+// Config is a declarative configuration of credentials which can be loaded from YAML (ParseConfigYAML)
+// or JSON (ParseConfig).
+// String fields may reference environment variables as $VAR or ${VAR}.
+//
+// Example (YAML):
+//
+//	source: service_account_key_file
+//	service_account_key_file: ${SECRETS_DIR}/sa.json
+//	installation: yandex-cloud
+//	token_ttl: 1h
+//	retry:
+//	  attempts: 3
+//	  backoff: 1s
+//	internal_ca: true
+//	fallback:
+//	  - source: metadata
+type Config struct {
+	// Source is the type of credentials, one of Source* constants.
+	Source string `json:"source" yaml:"source"`
+
+	// ServiceAccountKeyFile is used with SourceServiceAccountKeyFile.
+	ServiceAccountKeyFile string `json:"service_account_key_file,omitempty" yaml:"service_account_key_file,omitempty"`
+	// ServiceAccountKey is used with SourceServiceAccountKey.
+	ServiceAccountKey string `json:"service_account_key,omitempty" yaml:"service_account_key,omitempty"`
+	// MetadataURL is used with SourceMetadata, the default metadata url is used if empty.
+	MetadataURL string `json:"metadata_url,omitempty" yaml:"metadata_url,omitempty"`
+	// AccessToken is used with SourceAccessToken.
+	AccessToken string `json:"access_token,omitempty" yaml:"access_token,omitempty"`
+
+	// Endpoint, Installation, Audience, CAFile, TokenTTL, Retry and Cache configure
+	// service account key credentials.
+	Endpoint     string       `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Installation string       `json:"installation,omitempty" yaml:"installation,omitempty"`
+	Audience     string       `json:"audience,omitempty" yaml:"audience,omitempty"`
+	CAFile       string       `json:"ca_file,omitempty" yaml:"ca_file,omitempty"`
+	TokenTTL     Duration     `json:"token_ttl,omitempty" yaml:"token_ttl,omitempty"`
+	Retry        *RetryConfig `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Cache        *CacheConfig `json:"cache,omitempty" yaml:"cache,omitempty"`
+
+	// InternalCA appends Yandex Cloud certificates to the driver, used by Config.Option.
+	InternalCA bool `json:"internal_ca,omitempty" yaml:"internal_ca,omitempty"`
+
+	// Fallback credentials are tried in order if these credentials can not be created.
+	Fallback []Config `json:"fallback,omitempty" yaml:"fallback,omitempty"`
+}
+
+// ParseConfig parses JSON configuration, expands environment variables and validates it.
+// Unknown fields are rejected.
+func ParseConfig(data []byte) (*Config, error) {
+	var c Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("cannot parse credentials config: %w", err)
+	}
+	c = c.expand(os.Getenv)
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// ParseConfigYAML is ParseConfig for YAML configuration.
+func ParseConfigYAML(data []byte) (*Config, error) {
+	var c Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("cannot parse credentials config: %w", err)
+	}
+	c = c.expand(os.Getenv)
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// expand returns a copy of config with environment variables expanded in string fields.
+func (c Config) expand(getenv func(string) string) Config {
+	for _, s := range []*string{
+		&c.Source, &c.ServiceAccountKeyFile, &c.ServiceAccountKey, &c.MetadataURL, &c.AccessToken,
+		&c.Endpoint, &c.Installation, &c.Audience, &c.CAFile,
+	} {
+		*s = os.Expand(*s, getenv)
+	}
+	if len(c.Fallback) > 0 {
+		fallback := make([]Config, 0, len(c.Fallback))
+		for _, f := range c.Fallback {
+			fallback = append(fallback, f.expand(getenv))
+		}
+		c.Fallback = fallback
+	}
+
+	return c
+}
+
+// Validate checks that required fields of the source are set and values are in range.
+func (c *Config) Validate() error {
+	return c.validate("config")
+}
+
+func (c *Config) validate(path string) error {
+	required := map[string]string{
+		SourceServiceAccountKeyFile: c.ServiceAccountKeyFile,
+		SourceServiceAccountKey:     c.ServiceAccountKey,
+		SourceAccessToken:           c.AccessToken,
+	}
+	switch c.Source {
+	case SourceServiceAccountKeyFile, SourceServiceAccountKey, SourceAccessToken:
+		if required[c.Source] == "" {
+			return fmt.Errorf("%s: field %s is required for source %s", path, c.Source, c.Source)
+		}
+	case SourceMetadata, SourceAnonymous:
+	case "":
+		return fmt.Errorf("%s: field source is required", path)
+	default:
+		return fmt.Errorf("%s: unknown source '%s'", path, c.Source)
+	}
+	if c.isServiceAccountKey() {
+		if c.Endpoint != "" && c.Installation != "" {
+			return fmt.Errorf("%s: fields endpoint and installation are mutually exclusive", path)
+		}
+	} else if c.Endpoint != "" || c.Installation != "" || c.Audience != "" || c.CAFile != "" ||
+		c.TokenTTL != 0 || c.Retry != nil || c.Cache != nil {
+		return fmt.Errorf("%s: iam client fields are supported only for service account key sources", path)
+	}
+	if c.TokenTTL < 0 {
+		return fmt.Errorf("%s: token_ttl must not be negative", path)
+	}
+	if c.Retry != nil && c.Retry.Attempts < 1 {
+		return fmt.Errorf("%s: retry.attempts must be positive", path)
+	}
+	if c.Cache != nil && (c.Cache.RefreshRatio <= 0 || c.Cache.RefreshRatio >= 1) {
+		return fmt.Errorf("%s: cache.refresh_ratio must be in (0, 1)", path)
+	}
+	for i := range c.Fallback {
+		if err := c.Fallback[i].validate(fmt.Sprintf("%s.fallback[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) isServiceAccountKey() bool {
+	return c.Source == SourceServiceAccountKeyFile || c.Source == SourceServiceAccountKey
+}
+
+// ClientOptions returns options of NewClient for service account key sources: the key, endpoint,
+// installation, audience, CA file, token TTL, retry and cache settings.
+func (c *Config) ClientOptions() []ClientOption {
+	var opts []ClientOption
+	switch c.Source {
+	case SourceServiceAccountKeyFile:
+		opts = append(opts, WithServiceFile(c.ServiceAccountKeyFile))
+	case SourceServiceAccountKey:
+		opts = append(opts, WithServiceKey(c.ServiceAccountKey))
+	}
+	if c.Endpoint != "" {
+		opts = append(opts, WithEndpoint(c.Endpoint))
+	}
+	if c.Installation != "" {
+		opts = append(opts, WithInstallationName(c.Installation))
+	}
+	if c.Audience != "" {
+		opts = append(opts, WithAudience(c.Audience))
+	}
+	if c.CAFile != "" {
+		opts = append(opts, WithCertPoolFile(c.CAFile))
+	}
+	if c.TokenTTL != 0 {
+		opts = append(opts, WithTokenTTL(time.Duration(c.TokenTTL)))
+	}
+	if c.Retry != nil {
+		opts = append(opts, WithRetry(c.Retry.Attempts, time.Duration(c.Retry.Backoff)))
+	}
+	if c.Cache != nil {
+		opts = append(opts, WithRefreshRatio(c.Cache.RefreshRatio))
+	}
+
+	return opts
+}
+
+// credentials creates credentials of the source without fallbacks.
+func (c *Config) credentials() (credentials.Credentials, error) {
+	switch c.Source {
+	case SourceServiceAccountKeyFile, SourceServiceAccountKey:
+		return NewClient(append(c.ClientOptions(), WithSourceInfo("yc.Config("+c.Source+")"))...)
+	case SourceMetadata:
+		if c.MetadataURL != "" {
+			return NewInstanceServiceAccountURL(c.MetadataURL), nil
+		}
+
+		return NewInstanceServiceAccount(), nil
+	case SourceAccessToken:
+		return credentials.NewAccessTokenCredentials(c.AccessToken,
+			credentials.WithSourceInfo("yc.Config("+c.Source+")"),
+		), nil
+	case SourceAnonymous:
+		return credentials.NewAnonymousCredentials(
+			credentials.WithSourceInfo("yc.Config(" + c.Source + ")"),
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown source '%s'", c.Source)
+	}
+}
+
+// Credentials validates config and creates credentials. If credentials of the source can not be
+// created (e.g. service account key file is missing), fallback configs are tried in order.
+func (c *Config) Credentials() (credentials.Credentials, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c.credentialsWithFallback()
+}
+
+func (c *Config) credentialsWithFallback() (credentials.Credentials, error) {
+	creds, err := c.credentials()
+	if err == nil {
+		return creds, nil
+	}
+	issues := []string{c.Source + ": " + err.Error()}
+	for i := range c.Fallback {
+		creds, fallbackErr := c.Fallback[i].credentialsWithFallback()
+		if fallbackErr == nil {
+			return creds, nil
+		}
+		issues = append(issues, fallbackErr.Error())
+	}
+	if len(issues) == 1 {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("cannot create credentials: %s", strings.Join(issues, "; "))
+}
+
+// Option validates config and returns ydb.Option with credentials and, if InternalCA is set,
+// Yandex Cloud certificates.
+func (c *Config) Option() (ydb.Option, error) {
+	creds, err := c.Credentials()
+	if err != nil {
+		return nil, err
+	}
+	opts := []ydb.Option{ydb.WithCredentials(creds)}
+	if c.InternalCA {
+		opts = append(opts, WithInternalCA())
+	}
+
+	return ydb.MergeOptions(opts...), nil
+}
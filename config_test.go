@@ -0,0 +1,178 @@
+package yc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseConfig(t *testing.T) {
+	t.Setenv("YC_TEST_SECRETS", "/secrets")
+
+	c, err := ParseConfig([]byte(`{
+		"source": "service_account_key_file",
+		"service_account_key_file": "${YC_TEST_SECRETS}/sa.json",
+		"installation": "yandex-cloud-kz",
+		"token_ttl": "30m",
+		"retry": {"attempts": 3, "backoff": "1s"},
+		"cache": {"refresh_ratio": 0.8},
+		"internal_ca": true,
+		"fallback": [{"source": "metadata", "metadata_url": "$YC_TEST_SECRETS/token"}]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, &Config{
+		Source:                SourceServiceAccountKeyFile,
+		ServiceAccountKeyFile: "/secrets/sa.json",
+		Installation:          "yandex-cloud-kz",
+		TokenTTL:              Duration(30 * time.Minute),
+		Retry:                 &RetryConfig{Attempts: 3, Backoff: Duration(time.Second)},
+		Cache:                 &CacheConfig{RefreshRatio: 0.8},
+		InternalCA:            true,
+		Fallback:              []Config{{Source: SourceMetadata, MetadataURL: "/secrets/token"}},
+	}, c)
+	require.Len(t, c.ClientOptions(), 5)
+
+	_, err = ParseConfig([]byte(`{"source": "service_account_key_file", "token_ttl": "forever"}`))
+	require.Error(t, err)
+}
+
+func TestConfigYAML(t *testing.T) {
+	var c Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+source: access_token
+access_token: token
+fallback:
+  - source: service_account_key
+    service_account_key: "{}"
+    token_ttl: 1h
+`), &c))
+	require.Equal(t, Config{
+		Source:      SourceAccessToken,
+		AccessToken: "token",
+		Fallback: []Config{{
+			Source:            SourceServiceAccountKey,
+			ServiceAccountKey: "{}",
+			TokenTTL:          Duration(time.Hour),
+		}},
+	}, c)
+}
+
+func TestParseConfigYAML(t *testing.T) {
+	t.Setenv("YC_TEST_SECRETS", "/secrets")
+
+	c, err := ParseConfigYAML([]byte(`
+source: service_account_key_file
+service_account_key_file: ${YC_TEST_SECRETS}/sa.json
+token_ttl: 30m
+fallback:
+  - source: metadata
+    metadata_url: $YC_TEST_SECRETS/token
+`))
+	require.NoError(t, err)
+	require.Equal(t, &Config{
+		Source:                SourceServiceAccountKeyFile,
+		ServiceAccountKeyFile: "/secrets/sa.json",
+		TokenTTL:              Duration(30 * time.Minute),
+		Fallback:              []Config{{Source: SourceMetadata, MetadataURL: "/secrets/token"}},
+	}, c)
+
+	_, err = ParseConfigYAML([]byte("source: [metadata"))
+	require.Error(t, err)
+	_, err = ParseConfigYAML([]byte("source: access_token"))
+	require.Error(t, err)
+	_, err = ParseConfigYAML(nil)
+	require.Error(t, err)
+}
+
+func TestParseConfigUnknownFields(t *testing.T) {
+	_, err := ParseConfig([]byte(`{"source": "service_account_key_file", "service_account_key_file": "sa.json", ` +
+		`"token_tll": "30m"}`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "token_tll")
+
+	_, err = ParseConfig([]byte(`{"source": "metadata", "fallback": [{"source": "anonymous", "internal_cs": true}]}`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "internal_cs")
+
+	_, err = ParseConfigYAML([]byte(`
+source: service_account_key_file
+service_account_key_file: sa.json
+token_tll: 30m
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "token_tll")
+
+	_, err = ParseConfigYAML([]byte(`
+source: metadata
+fallback:
+  - source: anonymous
+    internal_cs: true
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "internal_cs")
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		config Config
+	}{
+		{name: "NoSource", config: Config{}},
+		{name: "UnknownSource", config: Config{Source: "oauth"}},
+		{name: "MissingKeyFile", config: Config{Source: SourceServiceAccountKeyFile}},
+		{name: "MissingAccessToken", config: Config{Source: SourceAccessToken}},
+		{name: "EndpointAndInstallation", config: Config{
+			Source:            SourceServiceAccountKey,
+			ServiceAccountKey: "{}",
+			Endpoint:          "iam:443",
+			Installation:      "yandex-cloud",
+		}},
+		{name: "ClientFieldsOfMetadata", config: Config{Source: SourceMetadata, TokenTTL: Duration(time.Hour)}},
+		{name: "RetryAttempts", config: Config{
+			Source:            SourceServiceAccountKey,
+			ServiceAccountKey: "{}",
+			Retry:             &RetryConfig{},
+		}},
+		{name: "RefreshRatio", config: Config{
+			Source:            SourceServiceAccountKey,
+			ServiceAccountKey: "{}",
+			Cache:             &CacheConfig{RefreshRatio: 2},
+		}},
+		{name: "Fallback", config: Config{
+			Source:   SourceAnonymous,
+			Fallback: []Config{{Source: SourceAccessToken}},
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.config.Validate())
+		})
+	}
+}
+
+func TestConfigCredentialsFallback(t *testing.T) {
+	c := Config{
+		Source:                SourceServiceAccountKeyFile,
+		ServiceAccountKeyFile: "/not/exists/sa.json",
+		Fallback: []Config{
+			{Source: SourceServiceAccountKey, ServiceAccountKey: "{}"},
+			{Source: SourceAccessToken, AccessToken: "token"},
+		},
+	}
+	creds, err := c.Credentials()
+	require.NoError(t, err)
+	token, err := creds.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token", token)
+
+	c.Fallback = c.Fallback[:1]
+	_, err = c.Credentials()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), SourceServiceAccountKey)
+
+	opt, err := (&Config{Source: SourceAnonymous, InternalCA: true}).Option()
+	require.NoError(t, err)
+	require.NotNil(t, opt)
+}
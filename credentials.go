@@ -9,3 +9,21 @@ import (
 func NewClient(opts ...ClientOption) (credentials.Credentials, error) {
 	return auth.NewClient(opts...)
 }
+
+// Impersonator issues iam tokens of service accounts to the identity of base credentials,
+// tokens are cached per service account.
+type Impersonator = auth.Impersonator
+
+// NewImpersonator creates Impersonator on top of base credentials, e.g. made by NewClient or
+// NewInstanceServiceAccount. Connection parameters of the iam endpoint are taken from opts.
+func NewImpersonator(base credentials.Credentials, opts ...ClientOption) (*Impersonator, error) {
+	return auth.NewImpersonator(base, opts...)
+}
+
+// NewImpersonatedCredentials returns credentials of the service account serviceAccountID issued to
+// the identity of base credentials.
+func NewImpersonatedCredentials(
+	base credentials.Credentials, serviceAccountID string, opts ...ClientOption,
+) (credentials.Credentials, error) {
+	return auth.NewImpersonatedCredentials(base, serviceAccountID, opts...)
+}
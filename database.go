@@ -0,0 +1,71 @@
+package yc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ydb-platform/ydb-go-sdk/v3"
+	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+
+	"github.com/ydb-platform/ydb-go-yc/internal/auth"
+)
+
+// DatabaseConnectionString returns the connection string of the serverless or dedicated YDB database
+// by its id using DatabaseService.Get of YDB management API, authorized with creds.
+//
+// The management endpoint is "ydb.api.cloud.yandex.net:443" or, if WithEndpointDiscovery is provided,
+// the discovered "ydb" endpoint. Connection parameters are taken from opts.
+func DatabaseConnectionString(
+	ctx context.Context, databaseID string, creds credentials.Credentials, opts ...ClientOption,
+) (string, error) {
+	return auth.DatabaseConnectionString(ctx, databaseID, creds, opts...)
+}
+
+// DatabaseOption configures OpenByDatabaseID.
+type DatabaseOption func(*databaseOptions)
+
+type databaseOptions struct {
+	clientOpts []ClientOption
+	driverOpts []ydb.Option
+}
+
+// WithDatabaseClientOptions set connection parameters of DatabaseConnectionString used to resolve
+// the database (installation, endpoint discovery, cert pool, etc.). Several calls are combined.
+func WithDatabaseClientOptions(opts ...ClientOption) DatabaseOption {
+	return func(o *databaseOptions) {
+		o.clientOpts = append(o.clientOpts, opts...)
+	}
+}
+
+// WithDriverOptions appends options of ydb.Open. Several calls are combined.
+func WithDriverOptions(opts ...ydb.Option) DatabaseOption {
+	return func(o *databaseOptions) {
+		o.driverOpts = append(o.driverOpts, opts...)
+	}
+}
+
+// OpenByDatabaseID resolves the connection string of the database by its id with
+// DatabaseConnectionString and opens the driver with creds, WithInternalCA and options of
+// WithDriverOptions.
+//
+// The database is resolved with default connection parameters unless WithDatabaseClientOptions
+// is provided.
+func OpenByDatabaseID(
+	ctx context.Context, databaseID string, creds credentials.Credentials, opts ...DatabaseOption,
+) (*ydb.Driver, error) {
+	var o databaseOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	connectionString, err := DatabaseConnectionString(ctx, databaseID, creds, o.clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve database '%s': %w", databaseID, err)
+	}
+
+	return ydb.Open(ctx, connectionString,
+		append([]ydb.Option{
+			ydb.WithCredentials(creds),
+			WithInternalCA(),
+		}, o.driverOpts...)...,
+	)
+}
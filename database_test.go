@@ -0,0 +1,75 @@
+package yc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/endpoint"
+	ydbv1 "github.com/yandex-cloud/go-genproto/yandex/cloud/ydb/v1"
+	"github.com/ydb-platform/ydb-go-sdk/v3"
+	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+	"google.golang.org/grpc"
+
+	"github.com/ydb-platform/ydb-go-yc/ydbyctest"
+)
+
+type stubEndpointService struct {
+	endpoint.UnimplementedApiEndpointServiceServer
+
+	ydb string
+}
+
+func (s *stubEndpointService) List(context.Context, *endpoint.ListApiEndpointsRequest) (
+	*endpoint.ListApiEndpointsResponse, error,
+) {
+	return &endpoint.ListApiEndpointsResponse{
+		Endpoints: []*endpoint.ApiEndpoint{{Id: "ydb", Address: s.ydb}},
+	}, nil
+}
+
+type stubDatabaseService struct {
+	ydbv1.UnimplementedDatabaseServiceServer
+}
+
+func (stubDatabaseService) Get(_ context.Context, req *ydbv1.GetDatabaseRequest) (*ydbv1.Database, error) {
+	return &ydbv1.Database{
+		Id:       req.GetDatabaseId(),
+		Endpoint: "grpcs://127.0.0.1:1/?database=/private/" + req.GetDatabaseId(),
+	}, nil
+}
+
+func TestOpenByDatabaseID(t *testing.T) {
+	endpoints := &stubEndpointService{}
+	srv, err := ydbyctest.NewServer(func(s *grpc.Server) {
+		endpoint.RegisterApiEndpointServiceServer(s, endpoints)
+		ydbv1.RegisterDatabaseServiceServer(s, stubDatabaseService{})
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, srv.Close())
+	}()
+	endpoints.ydb = srv.Endpoint
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The database is resolved at the discovered endpoint, the resolved endpoint does not exist.
+	openCtx, openCancel := context.WithTimeout(ctx, time.Second)
+	defer openCancel()
+	_, err = OpenByDatabaseID(openCtx, "etn-db", credentials.NewAccessTokenCredentials("token"),
+		WithDatabaseClientOptions(WithEndpointDiscovery(srv.Endpoint), WithCertPool(srv.CertPool)),
+		WithDriverOptions(ydb.WithDialTimeout(100*time.Millisecond)),
+	)
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "cannot resolve database")
+	require.Contains(t, err.Error(), "127.0.0.1:1")
+
+	// Without the cert pool the certificate of the server is not trusted.
+	_, err = OpenByDatabaseID(ctx, "etn-db", credentials.NewAccessTokenCredentials("token"),
+		WithDatabaseClientOptions(WithEndpointDiscovery(srv.Endpoint)),
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot resolve database 'etn-db'")
+}
@@ -0,0 +1,143 @@
+package yc
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/ydb-platform/ydb-go-sdk/v3"
+)
+
+// Query parameters of a connection string recognized by WithDSNCredentials.
+const (
+	// DSNServiceAccountKeyFile is a path to the service account key file.
+	DSNServiceAccountKeyFile = "yc_sa_key_file"
+	// DSNServiceAccountKey is the content of the service account key (url encoded json).
+	DSNServiceAccountKey = "yc_sa_key"
+	// DSNMetadata enables metadata credentials ("1" or "true").
+	DSNMetadata = "yc_metadata"
+	// DSNMetadataURL is the metadata service url, implies metadata credentials.
+	DSNMetadataURL = "yc_metadata_url"
+	// DSNIAMEndpoint is the iam endpoint used with service account key credentials.
+	DSNIAMEndpoint = "yc_iam_endpoint"
+	// DSNInstallation is the name of the installation used with service account key credentials
+	// ("yandex-cloud", "yandex-cloud-kz").
+	DSNInstallation = "yc_installation"
+	// DSNInternalCA appends Yandex Cloud certificates ("1" or "true").
+	DSNInternalCA = "yc_internal_ca"
+)
+
+// dsnParams contains Yandex Cloud parameters of a connection string.
+type dsnParams struct {
+	serviceAccountKeyFile string
+	serviceAccountKey     string
+	metadata              bool
+	metadataURL           string
+	iamEndpoint           string
+	installation          string
+	internalCA            bool
+}
+
+func parseDSN(dsn string) (p dsnParams, err error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return p, fmt.Errorf("cannot parse connection string: %w", err)
+	}
+	query := u.Query()
+	parseBool := func(name string) (bool, error) {
+		if !query.Has(name) {
+			return false, nil
+		}
+		v, err := strconv.ParseBool(query.Get(name))
+		if err != nil {
+			return false, fmt.Errorf("connection string parameter %s is not a boolean: %w", name, err)
+		}
+
+		return v, nil
+	}
+	p.serviceAccountKeyFile = query.Get(DSNServiceAccountKeyFile)
+	p.serviceAccountKey = query.Get(DSNServiceAccountKey)
+	p.metadataURL = query.Get(DSNMetadataURL)
+	p.iamEndpoint = query.Get(DSNIAMEndpoint)
+	p.installation = query.Get(DSNInstallation)
+	if p.metadata, err = parseBool(DSNMetadata); err != nil {
+		return p, err
+	}
+	if p.internalCA, err = parseBool(DSNInternalCA); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
+
+func (p *dsnParams) options() ([]ydb.Option, error) {
+	var (
+		opts    []ydb.Option
+		sources []string
+	)
+	if p.internalCA {
+		opts = append(opts, WithInternalCA())
+	}
+	var clientOpts []ClientOption
+	if p.iamEndpoint != "" {
+		clientOpts = append(clientOpts, WithEndpoint(p.iamEndpoint))
+	}
+	if p.installation != "" {
+		clientOpts = append(clientOpts, WithInstallationName(p.installation))
+	}
+	if p.serviceAccountKeyFile != "" {
+		sources = append(sources, DSNServiceAccountKeyFile)
+		opts = append(opts, WithServiceAccountKeyFileCredentials(p.serviceAccountKeyFile, clientOpts...))
+	}
+	if p.serviceAccountKey != "" {
+		sources = append(sources, DSNServiceAccountKey)
+		opts = append(opts, WithServiceAccountKeyCredentials(p.serviceAccountKey, clientOpts...))
+	}
+	switch {
+	case p.metadataURL != "":
+		sources = append(sources, DSNMetadataURL)
+		opts = append(opts, WithMetadataCredentialsURL(p.metadataURL))
+	case p.metadata:
+		sources = append(sources, DSNMetadata)
+		opts = append(opts, WithMetadataCredentials())
+	}
+	if len(sources) > 1 {
+		return nil, fmt.Errorf("connection string contains several credentials: %v", sources)
+	}
+	if len(clientOpts) > 0 && p.serviceAccountKeyFile == "" && p.serviceAccountKey == "" {
+		return nil, fmt.Errorf("connection string parameters %s and %s require service account key",
+			DSNIAMEndpoint, DSNInstallation,
+		)
+	}
+
+	return opts, nil
+}
+
+// WithDSNCredentials configures credentials and certificates from the query parameters of
+// the connection string (see DSNServiceAccountKeyFile and other DSN constants), e.g.
+//
+//	grpcs://ydb.serverless.yandexcloud.net:2135/?database=/ru-central1/b1g/etn&yc_sa_key_file=/sa.json&yc_internal_ca=1
+//
+// The same connection string should be passed to ydb.Open (or sql.Open), which ignores these parameters:
+//
+//	db, err := ydb.Open(ctx, dsn, yc.WithDSNCredentials(dsn))
+func WithDSNCredentials(dsn string) ydb.Option {
+	p, err := parseDSN(dsn)
+	if err != nil {
+		return errorOption(err)
+	}
+	opts, err := p.options()
+	if err != nil {
+		return errorOption(err)
+	}
+
+	return ydb.MergeOptions(opts...)
+}
+
+// errorOption makes ydb.Open fail with err.
+func errorOption(err error) ydb.Option {
+	return func(context.Context, *ydb.Driver) error {
+		return err
+	}
+}
@@ -0,0 +1,74 @@
+package yc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDSN(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		dsn     string
+		params  dsnParams
+		opts    int
+		wantErr bool
+	}{
+		{
+			name: "NoParameters",
+			dsn:  "grpcs://localhost:2135/local",
+		},
+		{
+			name: "ServiceAccountKeyFile",
+			dsn:  "grpcs://localhost:2135/local?yc_sa_key_file=/sa.json&yc_iam_endpoint=iam:443&yc_internal_ca=1",
+			params: dsnParams{
+				serviceAccountKeyFile: "/sa.json",
+				iamEndpoint:           "iam:443",
+				internalCA:            true,
+			},
+			opts: 2,
+		},
+		{
+			name:   "Metadata",
+			dsn:    "grpcs://localhost:2135/?database=/local&yc_metadata=true",
+			params: dsnParams{metadata: true},
+			opts:   1,
+		},
+		{
+			name:   "MetadataURL",
+			dsn:    "grpcs://localhost:2135/local?yc_metadata_url=http%3A%2F%2F127.0.0.1%3A8080%2Ftoken",
+			params: dsnParams{metadataURL: "http://127.0.0.1:8080/token"},
+			opts:   1,
+		},
+		{
+			name:    "SeveralCredentials",
+			dsn:     "grpcs://localhost:2135/local?yc_sa_key_file=/sa.json&yc_metadata=1",
+			params:  dsnParams{serviceAccountKeyFile: "/sa.json", metadata: true},
+			wantErr: true,
+		},
+		{
+			name:    "EndpointWithoutKey",
+			dsn:     "grpcs://localhost:2135/local?yc_metadata=1&yc_installation=yandex-cloud-kz",
+			params:  dsnParams{metadata: true, installation: "yandex-cloud-kz"},
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parseDSN(tt.dsn)
+			require.NoError(t, err)
+			require.Equal(t, tt.params, p)
+
+			opts, err := p.options()
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, opts, tt.opts)
+		})
+	}
+
+	_, err := parseDSN("grpcs://localhost:2135/local?yc_internal_ca=yes")
+	require.Error(t, err)
+}
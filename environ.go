@@ -0,0 +1,101 @@
+package yc
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/ydb-platform/ydb-go-sdk/v3"
+)
+
+// Environment variables recognized by WithEnvironCredentials.
+const (
+	EnvServiceAccountKeyFileCredentials = "YDB_SERVICE_ACCOUNT_KEY_FILE_CREDENTIALS"
+	EnvServiceAccountKeyCredentials     = "YDB_SERVICE_ACCOUNT_KEY_CREDENTIALS"
+	EnvAnonymousCredentials             = "YDB_ANONYMOUS_CREDENTIALS"
+	EnvMetadataCredentials              = "YDB_METADATA_CREDENTIALS"
+	EnvAccessTokenCredentials           = "YDB_ACCESS_TOKEN_CREDENTIALS"
+	EnvIAMEndpoint                      = "YC_IAM_ENDPOINT"
+)
+
+// ConfigFromEnviron returns the config of the first credentials found in environment variables in
+// the order of WithEnvironCredentials, lookup is usually os.LookupEnv. YC_IAM_ENDPOINT is applied to
+// service account key sources, InternalCA is set.
+func ConfigFromEnviron(lookup func(string) (string, bool)) (*Config, error) {
+	c, err := environSource(lookup)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint, ok := lookup(EnvIAMEndpoint); ok && endpoint != "" && c.isServiceAccountKey() {
+		c.Endpoint = endpoint
+	}
+	c.InternalCA = true
+	if err = c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// environSource returns the config with the source of the first credentials found in environment.
+func environSource(lookup func(string) (string, bool)) (*Config, error) {
+	isSet := func(name string) (bool, error) {
+		v, ok := lookup(name)
+		if !ok || v == "" {
+			return false, nil
+		}
+		set, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("environment variable %s is not a boolean: %w", name, err)
+		}
+
+		return set, nil
+	}
+
+	if path, ok := lookup(EnvServiceAccountKeyFileCredentials); ok && path != "" {
+		return &Config{Source: SourceServiceAccountKeyFile, ServiceAccountKeyFile: path}, nil
+	}
+	if key, ok := lookup(EnvServiceAccountKeyCredentials); ok && key != "" {
+		return &Config{Source: SourceServiceAccountKey, ServiceAccountKey: key}, nil
+	}
+	if set, err := isSet(EnvAnonymousCredentials); err != nil {
+		return nil, err
+	} else if set {
+		return &Config{Source: SourceAnonymous}, nil
+	}
+	if set, err := isSet(EnvMetadataCredentials); err != nil {
+		return nil, err
+	} else if set {
+		return &Config{Source: SourceMetadata}, nil
+	}
+	if token, ok := lookup(EnvAccessTokenCredentials); ok && token != "" {
+		return &Config{Source: SourceAccessToken, AccessToken: token}, nil
+	}
+
+	return &Config{Source: SourceMetadata}, nil
+}
+
+// WithEnvironCredentials configures credentials from environment variables and appends
+// Yandex Cloud certificates (WithInternalCA). The first defined variable wins:
+//
+//   - YDB_SERVICE_ACCOUNT_KEY_FILE_CREDENTIALS: path to the service account key file;
+//   - YDB_SERVICE_ACCOUNT_KEY_CREDENTIALS: content of the service account key;
+//   - YDB_ANONYMOUS_CREDENTIALS="1": anonymous credentials;
+//   - YDB_METADATA_CREDENTIALS="1": metadata credentials;
+//   - YDB_ACCESS_TOKEN_CREDENTIALS: static access token.
+//
+// If none of them is defined, metadata credentials are used. YC_IAM_ENDPOINT overrides
+// the iam endpoint of service account key credentials. The option is Config.Option of
+// ConfigFromEnviron(os.LookupEnv).
+func WithEnvironCredentials() ydb.Option {
+	c, err := ConfigFromEnviron(os.LookupEnv)
+	if err != nil {
+		return errorOption(err)
+	}
+	opt, err := c.Option()
+	if err != nil {
+		return errorOption(err)
+	}
+
+	return opt
+}
@@ -0,0 +1,84 @@
+package yc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromEnviron(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		environ  map[string]string
+		source   string
+		endpoint string
+		wantErr  bool
+	}{
+		{
+			name:   "Default",
+			source: SourceMetadata,
+		},
+		{
+			name: "ServiceAccountKeyFile",
+			environ: map[string]string{
+				EnvServiceAccountKeyFileCredentials: "/sa.json",
+				EnvMetadataCredentials:              "1",
+				EnvIAMEndpoint:                      "iam:443",
+			},
+			source:   SourceServiceAccountKeyFile,
+			endpoint: "iam:443",
+		},
+		{
+			name:    "ServiceAccountKey",
+			environ: map[string]string{EnvServiceAccountKeyCredentials: "{}"},
+			source:  SourceServiceAccountKey,
+		},
+		{
+			name:    "Anonymous",
+			environ: map[string]string{EnvAnonymousCredentials: "1", EnvAccessTokenCredentials: "token"},
+			source:  SourceAnonymous,
+		},
+		{
+			name:    "AnonymousDisabled",
+			environ: map[string]string{EnvAnonymousCredentials: "0", EnvAccessTokenCredentials: "token"},
+			source:  SourceAccessToken,
+		},
+		{
+			name:    "Metadata",
+			environ: map[string]string{EnvMetadataCredentials: "true"},
+			source:  SourceMetadata,
+		},
+		{
+			name:    "AccessToken",
+			environ: map[string]string{EnvAccessTokenCredentials: "token"},
+			source:  SourceAccessToken,
+		},
+		{
+			name:    "MetadataIgnoresEndpoint",
+			environ: map[string]string{EnvMetadataCredentials: "1", EnvIAMEndpoint: "iam:443"},
+			source:  SourceMetadata,
+		},
+		{
+			name:    "NotBoolean",
+			environ: map[string]string{EnvMetadataCredentials: "yes"},
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ConfigFromEnviron(func(name string) (string, bool) {
+				v, ok := tt.environ[name]
+
+				return v, ok
+			})
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.source, c.Source)
+			require.Equal(t, tt.endpoint, c.Endpoint)
+			require.True(t, c.InternalCA)
+		})
+	}
+}
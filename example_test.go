@@ -57,3 +57,27 @@ func Example_withServiceAccountKeyFileCredentials() {
 	}
 	_ = db.Close(context.TODO())
 }
+
+func Example_openByDatabaseID() {
+	creds, err := yc.NewClient(yc.WithServiceFile("~/.ydb/sa.json"))
+	if err != nil {
+		panic(err)
+	}
+	db, err := yc.OpenByDatabaseID(context.TODO(), "etnaeujopcre7mubi9lj", creds)
+	if err != nil {
+		panic(err)
+	}
+	_ = db.Close(context.TODO())
+}
+
+func Example_withDSNCredentials() {
+	dsn := "grpcs://ydb.serverless.yandexcloud.net:2135/?database=/ru-central1/b1g8skpblkos03malf3s/etnaeujopcre7mubi9lj" +
+		"&yc_sa_key_file=/var/run/secrets/sa.json&yc_internal_ca=1"
+	db, err := ydb.Open(context.TODO(), dsn,
+		yc.WithDSNCredentials(dsn),
+	)
+	if err != nil {
+		panic(err)
+	}
+	_ = db.Close(context.TODO())
+}
@@ -9,6 +9,8 @@ require (
 	github.com/yandex-cloud/go-genproto v0.0.0-20240819112322-98a264d392f6
 	github.com/ydb-platform/ydb-go-sdk/v3 v3.47.3
 	github.com/ydb-platform/ydb-go-yc-metadata v0.6.1
+	golang.org/x/net v0.8.0
 	google.golang.org/grpc v1.55.0
 	google.golang.org/protobuf v1.30.0
+	gopkg.in/yaml.v3 v3.0.1
 )
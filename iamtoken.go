@@ -0,0 +1,65 @@
+package yc
+
+import (
+	"time"
+
+	"github.com/ydb-platform/ydb-go-sdk/v3"
+
+	"github.com/ydb-platform/ydb-go-yc/internal/auth"
+	"github.com/ydb-platform/ydb-go-yc/trace"
+)
+
+// IAMTokenCredentials provides a pre-issued iam token read from a file or an environment variable.
+// It may be used as the target of WithFallbackCredentials.
+type IAMTokenCredentials = auth.IAMTokenCredentials
+
+type IAMTokenOption = auth.IAMTokenOption
+
+// DefaultExpiryWarning is the default time before expiration of a static iam token when
+// trace.Trace.OnTokenExpiring is called.
+const DefaultExpiryWarning = auth.DefaultExpiryWarning
+
+// ErrTokenExpired is returned from Token when the static iam token has expired.
+var ErrTokenExpired = auth.ErrTokenExpired
+
+// NewIAMTokenFileCredentials makes credentials which read iam token from the file. The file contains
+// the token or JSON output of `yc iam create-token --format json` and is read again when it changes.
+func NewIAMTokenFileCredentials(path string, opts ...IAMTokenOption) *IAMTokenCredentials {
+	return auth.NewIAMTokenFileCredentials(path, opts...)
+}
+
+// NewIAMTokenEnvCredentials makes credentials which read iam token from the environment variable.
+func NewIAMTokenEnvCredentials(name string, opts ...IAMTokenOption) *IAMTokenCredentials {
+	return auth.NewIAMTokenEnvCredentials(name, opts...)
+}
+
+// WithIAMTokenFile uses iam token from the file as credentials.
+func WithIAMTokenFile(path string, opts ...IAMTokenOption) ydb.Option {
+	return ydb.WithCredentials(NewIAMTokenFileCredentials(path, opts...))
+}
+
+// WithIAMTokenEnv uses iam token from the environment variable as credentials.
+func WithIAMTokenEnv(name string, opts ...IAMTokenOption) ydb.Option {
+	return ydb.WithCredentials(NewIAMTokenEnvCredentials(name, opts...))
+}
+
+// WithIAMTokenExpiresAt set expiration of the token when the source does not contain it. It applies
+// only to the token read first, rotated tokens have their own expiration or none.
+func WithIAMTokenExpiresAt(expiresAt time.Time) IAMTokenOption {
+	return auth.WithIAMTokenExpiresAt(expiresAt)
+}
+
+// WithIAMTokenExpiryWarning set the time before expiration when trace.Trace.OnTokenExpiring is called.
+func WithIAMTokenExpiryWarning(d time.Duration) IAMTokenOption {
+	return auth.WithIAMTokenExpiryWarning(d)
+}
+
+// WithIAMTokenTrace set trace of the static iam token events.
+func WithIAMTokenTrace(t trace.Trace) IAMTokenOption {
+	return auth.WithIAMTokenTrace(t)
+}
+
+// WithIAMTokenClock set the clock used to check expiration of the static iam token, e.g. a fake clock in tests.
+func WithIAMTokenClock(clock Clock) IAMTokenOption {
+	return auth.WithIAMTokenClock(clock)
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ydb-platform/ydb-go-yc/trace"
+)
+
+// Balancing defines the order in which several iam endpoints are tried.
+type Balancing int
+
+const (
+	// BalancingPriority tries endpoints in the listed order, the next endpoint is used only
+	// if the previous ones fail or are ejected.
+	BalancingPriority Balancing = iota
+	// BalancingRoundRobin starts each request from the next endpoint.
+	BalancingRoundRobin
+)
+
+// Default circuit breaker parameters of the iam endpoints.
+const (
+	DefaultEndpointMaxFailures = 3
+	DefaultEndpointCooldown    = 30 * time.Second
+)
+
+type endpointState struct {
+	endpoint  string
+	transport TokenExchanger
+
+	failures     int       // consecutive failures
+	ejectedUntil time.Time // zero if endpoint is not ejected
+}
+
+// multiTransport fails over between several iam endpoints. Endpoint is ejected for cooldown after
+// maxFailures consecutive failures. If all endpoints are ejected, all of them are tried anyway.
+type multiTransport struct {
+	balancing   Balancing
+	maxFailures int
+	cooldown    time.Duration
+	clock       Clock
+	trace       trace.Trace
+
+	mu        sync.Mutex
+	endpoints []*endpointState
+	next      int
+}
+
+func (m *multiTransport) CreateToken(ctx context.Context, jwt string) (string, time.Time, error) {
+	return m.do(ctx, func(t TokenExchanger) (string, time.Time, error) {
+		return t.CreateToken(ctx, jwt)
+	})
+}
+
+func (m *multiTransport) CreateTokenFromOAuth(ctx context.Context, oauthToken string) (string, time.Time, error) {
+	return m.do(ctx, func(t TokenExchanger) (string, time.Time, error) {
+		ot, ok := t.(oauthExchanger)
+		if !ok {
+			return "", time.Time{}, fmt.Errorf("iam: transport does not support OAuth tokens")
+		}
+
+		return ot.CreateTokenFromOAuth(ctx, oauthToken)
+	})
+}
+
+func (m *multiTransport) CreateTokenForServiceAccount(
+	ctx context.Context, iamToken, serviceAccountID string,
+) (string, time.Time, error) {
+	return m.do(ctx, func(t TokenExchanger) (string, time.Time, error) {
+		it, ok := t.(ImpersonationExchanger)
+		if !ok {
+			return "", time.Time{}, errImpersonationNotSupported
+		}
+
+		return it.CreateTokenForServiceAccount(ctx, iamToken, serviceAccountID)
+	})
+}
+
+// do calls createToken with transports of the endpoints until one of them succeeds.
+func (m *multiTransport) do(
+	ctx context.Context, createToken func(TokenExchanger) (string, time.Time, error),
+) (string, time.Time, error) {
+	var lastErr error
+	for _, e := range m.order() {
+		token, expires, err := createToken(e.transport)
+		if err == nil {
+			m.success(e)
+
+			return token, expires, nil
+		}
+		if ctx.Err() != nil || !isEndpointFailure(err) {
+			return "", time.Time{}, err
+		}
+		m.failure(e, err)
+		lastErr = fmt.Errorf("iam endpoint '%s' failed: %w", e.endpoint, err)
+	}
+
+	return "", time.Time{}, lastErr
+}
+
+// order returns endpoints which are not ejected in the order of balancing, followed by ejected ones.
+func (m *multiTransport) order() []*endpointState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	start := 0
+	if m.balancing == BalancingRoundRobin {
+		start = m.next
+		m.next = (m.next + 1) % len(m.endpoints)
+	}
+	var (
+		now     = m.clock.Now()
+		healthy = make([]*endpointState, 0, len(m.endpoints))
+		ejected []*endpointState
+	)
+	for i := range m.endpoints {
+		e := m.endpoints[(start+i)%len(m.endpoints)]
+		if e.ejectedUntil.After(now) {
+			ejected = append(ejected, e)
+		} else {
+			healthy = append(healthy, e)
+		}
+	}
+
+	return append(healthy, ejected...)
+}
+
+func (m *multiTransport) success(e *endpointState) {
+	m.mu.Lock()
+	restored := !e.ejectedUntil.IsZero()
+	e.failures = 0
+	e.ejectedUntil = time.Time{}
+	m.mu.Unlock()
+
+	if restored {
+		trace.TraceOnEndpointRestored(m.trace, e.endpoint)
+	}
+}
+
+func (m *multiTransport) failure(e *endpointState, err error) {
+	m.mu.Lock()
+	e.failures++
+	if e.failures < m.maxFailures {
+		m.mu.Unlock()
+
+		return
+	}
+	failures := e.failures
+	e.ejectedUntil = m.clock.Now().Add(m.cooldown)
+	until := e.ejectedUntil
+	m.mu.Unlock()
+
+	trace.TraceOnEndpointEjected(m.trace, e.endpoint, failures, until, err)
+}
+
+// isEndpointFailure reports whether err is caused by the endpoint itself and another endpoint
+// may succeed, as opposed to errors of the request (e.g. invalid jwt).
+func isEndpointFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ydb-platform/ydb-go-yc/trace"
+)
+
+func TestMultiTransport(t *testing.T) {
+	var (
+		clock    = clockwork.NewFakeClock()
+		calls    []string
+		failing  = map[string]error{}
+		ejected  []string
+		restored []string
+	)
+	endpoint := func(name string) *endpointState {
+		return &endpointState{
+			endpoint: name,
+			transport: TokenExchangerFunc(func(ctx context.Context, jwt string) (string, time.Time, error) {
+				calls = append(calls, name)
+				if err := failing[name]; err != nil {
+					return "", time.Time{}, err
+				}
+
+				return name, clock.Now().Add(time.Hour), nil
+			}),
+		}
+	}
+	newTransport := func(balancing Balancing) *multiTransport {
+		return &multiTransport{
+			balancing:   balancing,
+			maxFailures: 2,
+			cooldown:    time.Minute,
+			clock:       clock,
+			trace: trace.Trace{
+				OnEndpointEjected: func(info trace.EndpointEjectedInfo) {
+					ejected = append(ejected, info.Endpoint)
+				},
+				OnEndpointRestored: func(info trace.EndpointRestoredInfo) {
+					restored = append(restored, info.Endpoint)
+				},
+			},
+			endpoints: []*endpointState{endpoint("a"), endpoint("b"), endpoint("c")},
+		}
+	}
+	createToken := func(m *multiTransport) (string, error) {
+		calls = nil
+		token, _, err := m.CreateToken(context.Background(), "jwt")
+
+		return token, err
+	}
+
+	t.Run("Priority", func(t *testing.T) {
+		m := newTransport(BalancingPriority)
+		token, err := createToken(m)
+		require.NoError(t, err)
+		require.Equal(t, "a", token)
+
+		failing["a"] = status.Error(codes.Unavailable, "unavailable")
+		defer delete(failing, "a")
+
+		token, err = createToken(m)
+		require.NoError(t, err)
+		require.Equal(t, "b", token)
+		require.Equal(t, []string{"a", "b"}, calls)
+		require.Empty(t, ejected)
+
+		_, err = createToken(m)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a"}, ejected)
+
+		// Ejected endpoint is not tried until cooldown passes.
+		_, err = createToken(m)
+		require.NoError(t, err)
+		require.Equal(t, []string{"b"}, calls)
+
+		delete(failing, "a")
+		clock.Advance(time.Minute)
+		token, err = createToken(m)
+		require.NoError(t, err)
+		require.Equal(t, "a", token)
+		require.Equal(t, []string{"a"}, restored)
+	})
+
+	t.Run("RoundRobin", func(t *testing.T) {
+		m := newTransport(BalancingRoundRobin)
+		var tokens []string
+		for i := 0; i < 4; i++ {
+			token, err := createToken(m)
+			require.NoError(t, err)
+			tokens = append(tokens, token)
+		}
+		require.Equal(t, []string{"a", "b", "c", "a"}, tokens)
+	})
+
+	t.Run("RequestError", func(t *testing.T) {
+		m := newTransport(BalancingPriority)
+		failing["a"] = status.Error(codes.Unauthenticated, "invalid jwt")
+		defer delete(failing, "a")
+
+		_, err := createToken(m)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+		require.Equal(t, []string{"a"}, calls)
+	})
+
+	t.Run("AllFailed", func(t *testing.T) {
+		m := newTransport(BalancingPriority)
+		for _, name := range []string{"a", "b", "c"} {
+			failing[name] = status.Error(codes.Unavailable, "unavailable")
+		}
+		defer func() {
+			failing = map[string]error{}
+		}()
+
+		for i := 0; i < 3; i++ {
+			_, err := createToken(m)
+			require.Error(t, err)
+			require.Equal(t, []string{"a", "b", "c"}, calls)
+		}
+	})
+}
+
+func TestWithEndpoints(t *testing.T) {
+	c, err := NewClient(WithEndpoints("a:443", "b:443"), WithBalancing(BalancingRoundRobin))
+	require.NoError(t, err)
+
+	m, ok := c.(*client).transport.(*multiTransport)
+	require.True(t, ok)
+	require.Len(t, m.endpoints, 2)
+	require.Equal(t, BalancingRoundRobin, m.balancing)
+	require.Equal(t, DefaultEndpointMaxFailures, m.maxFailures)
+
+	_, err = NewClient(WithEndpoints("a:443", "b:443"), WithEndpoint("c:443"))
+	require.ErrorIs(t, err, ErrConflictingOptions)
+}
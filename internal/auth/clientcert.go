@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// certificateFile loads a client certificate from PEM files and reloads it when the files change.
+type certificateFile struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	certModTime time.Time
+	keyModTime  time.Time
+	cert        *tls.Certificate
+}
+
+func newCertificateFile(certFile, keyFile string) (*certificateFile, error) {
+	f := &certificateFile{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if _, err := f.GetClientCertificate(nil); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// GetClientCertificate implements tls.Config.GetClientCertificate. If the files were changed but
+// can not be loaded (e.g. the certificate is already replaced and the key is not yet), the previously
+// loaded certificate is returned and the files are loaded again on the next handshake.
+func (f *certificateFile) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	certInfo, err := os.Stat(f.certFile)
+	if err != nil {
+		return f.fallback(err)
+	}
+	keyInfo, err := os.Stat(f.keyFile)
+	if err != nil {
+		return f.fallback(err)
+	}
+	if f.cert != nil && certInfo.ModTime().Equal(f.certModTime) && keyInfo.ModTime().Equal(f.keyModTime) {
+		return f.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		return f.fallback(err)
+	}
+	f.cert = &cert
+	f.certModTime = certInfo.ModTime()
+	f.keyModTime = keyInfo.ModTime()
+
+	return f.cert, nil
+}
+
+func (f *certificateFile) fallback(err error) (*tls.Certificate, error) {
+	if f.cert != nil {
+		return f.cert, nil
+	}
+
+	return nil, fmt.Errorf("cannot load client certificate from '%s' and '%s': %w", f.certFile, f.keyFile, err)
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificateFileReload(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		certFile = filepath.Join(dir, "cert.pem")
+		keyFile  = filepath.Join(dir, "key.pem")
+	)
+	first, _ := selfSignedCert(t, "first")
+	second, _ := selfSignedCert(t, "second")
+
+	writeCertificate(t, first.Certificate[0], first.PrivateKey.(*ecdsa.PrivateKey), certFile, keyFile)
+	f, err := newCertificateFile(certFile, keyFile)
+	require.NoError(t, err)
+
+	cert, err := f.GetClientCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, first.Certificate, cert.Certificate)
+
+	// Certificate is replaced, but key is not yet: keep the previous certificate.
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: second.Certificate[0],
+	}), 0o600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	cert, err = f.GetClientCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, first.Certificate, cert.Certificate)
+
+	writeCertificate(t, second.Certificate[0], second.PrivateKey.(*ecdsa.PrivateKey), certFile, keyFile)
+	modTime = modTime.Add(time.Minute)
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+	cert, err = f.GetClientCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, second.Certificate, cert.Certificate)
+
+	_, err = newCertificateFile(filepath.Join(dir, "missing.pem"), keyFile)
+	require.Error(t, err)
+}
+
+func writeCertificate(t *testing.T, der []byte, key *ecdsa.PrivateKey, certFile, keyFile string) {
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+}
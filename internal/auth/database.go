@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	ydbv1 "github.com/yandex-cloud/go-genproto/yandex/cloud/ydb/v1"
+	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+	"google.golang.org/grpc"
+)
+
+// DefaultYDBEndpoint is the endpoint of Yandex Cloud YDB management API.
+const DefaultYDBEndpoint = "ydb.api.cloud.yandex.net:443"
+
+// perRPCCredentials authorizes grpc calls with a token of credentials.
+type perRPCCredentials struct {
+	credentials credentials.Credentials
+}
+
+func (c perRPCCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := c.credentials.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"authorization": "Bearer " + token,
+	}, nil
+}
+
+func (c perRPCCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// DatabaseConnectionString returns the connection string of the managed YDB database by its id
+// using DatabaseService.Get of YDB management API, authorized with creds.
+//
+// The management endpoint is DefaultYDBEndpoint or, if WithEndpointDiscovery is provided,
+// the discovered "ydb" endpoint. Connection parameters are taken from opts.
+func DatabaseConnectionString(
+	ctx context.Context, databaseID string, creds credentials.Credentials, opts ...ClientOption,
+) (string, error) {
+	c := &client{
+		certPool: defaultCertPool(),
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return "", err
+		}
+	}
+	endpoint := DefaultYDBEndpoint
+	if c.discoveryEndpoint != "" {
+		var err error
+		endpoint, err = discoverEndpoint(ctx, c.discoveryTransport(), ServiceYDB)
+		if err != nil {
+			return "", err
+		}
+	}
+	t := c.grpcTransport()
+	t.endpoint = endpoint
+	t.pins = nil
+
+	return t.databaseConnectionString(ctx, databaseID, creds)
+}
+
+func (t *grpcTransport) databaseConnectionString(
+	ctx context.Context, databaseID string, creds credentials.Credentials,
+) (string, error) {
+	t.dialOptions = append(append([]grpc.DialOption{}, t.dialOptions...),
+		grpc.WithPerRPCCredentials(perRPCCredentials{credentials: creds}),
+	)
+	var handshake handshakeError
+	conn, err := t.conn(ctx, &handshake)
+	if err != nil {
+		return "", handshake.wrap(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	db, err := ydbv1.NewDatabaseServiceClient(conn).Get(ctx, &ydbv1.GetDatabaseRequest{
+		DatabaseId: databaseID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot get database '%s': %w", databaseID, handshake.wrap(err))
+	}
+	if db.GetEndpoint() == "" {
+		return "", fmt.Errorf("database '%s' has no endpoint (status %s)", databaseID, db.GetStatus())
+	}
+
+	return db.GetEndpoint(), nil
+}
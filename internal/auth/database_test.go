@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	ydbv1 "github.com/yandex-cloud/go-genproto/yandex/cloud/ydb/v1"
+	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcCredentials "google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type stubDatabaseService struct {
+	ydbv1.UnimplementedDatabaseServiceServer
+}
+
+func (stubDatabaseService) Get(ctx context.Context, req *ydbv1.GetDatabaseRequest) (*ydbv1.Database, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if auth := md.Get("authorization"); len(auth) != 1 || auth[0] != "Bearer token" {
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	if req.GetDatabaseId() != "etn-db" {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+
+	return &ydbv1.Database{
+		Id:       req.GetDatabaseId(),
+		Endpoint: "grpcs://ydb.serverless.yandexcloud.net:2135/?database=/ru-central1/b1g-folder/etn-db",
+	}, nil
+}
+
+func TestDatabaseConnectionString(t *testing.T) {
+	cert, certPool := selfSignedCert(t, "ydb.test")
+	ln, err := (&net.ListenConfig{}).Listen(context.Background(), "tcp", "127.0.0.1:")
+	require.NoError(t, err)
+	srv := grpc.NewServer(grpc.Creds(grpcCredentials.NewServerTLSFromCert(&cert)))
+	ydbv1.RegisterDatabaseServiceServer(srv, stubDatabaseService{})
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	defer srv.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("Found", func(t *testing.T) {
+		gt := grpcTransport{endpoint: ln.Addr().String(), certPool: certPool, serverName: "ydb.test"}
+		connectionString, err := gt.databaseConnectionString(ctx, "etn-db", credentials.NewAccessTokenCredentials("token"))
+		require.NoError(t, err)
+		require.Equal(t, "grpcs://ydb.serverless.yandexcloud.net:2135/?database=/ru-central1/b1g-folder/etn-db",
+			connectionString,
+		)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		gt := grpcTransport{endpoint: ln.Addr().String(), certPool: certPool, serverName: "ydb.test"}
+		_, err := gt.databaseConnectionString(ctx, "unknown", credentials.NewAccessTokenCredentials("token"))
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
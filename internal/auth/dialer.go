@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// contextDialer dials the network address with context, as grpc.WithContextDialer expects.
+type contextDialer func(ctx context.Context, addr string) (net.Conn, error)
+
+func (d contextDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// DialContext implements proxy.ContextDialer, so contextDialer can forward SOCKS5 connections.
+func (d contextDialer) DialContext(ctx context.Context, _, addr string) (net.Conn, error) {
+	return d(ctx, addr)
+}
+
+func defaultDialer(ctx context.Context, addr string) (net.Conn, error) {
+	return (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+}
+
+// parseProxyURL checks that the proxy scheme is supported.
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("proxy url '%s' is not valid: %w", proxyURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy url '%s' has unsupported scheme, use http, https or socks5", proxyURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy url '%s' has no host", proxyURL)
+	}
+
+	return u, nil
+}
+
+// proxyFromEnvironment returns proxy for addr from HTTPS_PROXY and NO_PROXY environment variables
+// (and their lowercase versions), or nil if addr must be dialed directly.
+func proxyFromEnvironment(addr string) (*url.URL, error) {
+	return httpproxy.FromEnvironment().ProxyFunc()(&url.URL{Scheme: "https", Host: addr})
+}
+
+// dial connects to addr through the explicit proxy, the proxy from environment or directly.
+// The connection to the proxy (or to addr) is made with forward.
+func dial(ctx context.Context, forward contextDialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	if proxyURL == nil {
+		var err error
+		proxyURL, err = proxyFromEnvironment(addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if proxyURL == nil {
+		return forward(ctx, addr)
+	}
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if u := proxyURL.User; u != nil {
+			password, _ := u.Password()
+			auth = &proxy.Auth{User: u.Username(), Password: password}
+		}
+		d, err := proxy.SOCKS5("tcp", proxyHostPort(proxyURL), auth, forward)
+		if err != nil {
+			return nil, err
+		}
+
+		return d.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	default:
+		return dialHTTPConnect(ctx, forward, proxyURL, addr)
+	}
+}
+
+func proxyHostPort(proxyURL *url.URL) string {
+	if proxyURL.Port() != "" {
+		return proxyURL.Host
+	}
+	switch proxyURL.Scheme {
+	case "https":
+		return net.JoinHostPort(proxyURL.Hostname(), "443")
+	case "socks5", "socks5h":
+		return net.JoinHostPort(proxyURL.Hostname(), "1080")
+	default:
+		return net.JoinHostPort(proxyURL.Hostname(), "80")
+	}
+}
+
+// dialHTTPConnect makes a tunnel to addr with HTTP CONNECT request.
+func dialHTTPConnect(ctx context.Context, forward contextDialer, proxyURL *url.URL, addr string) (
+	_ net.Conn, err error,
+) {
+	conn, err := forward(ctx, proxyHostPort(proxyURL))
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to proxy '%s': %w", proxyURL.Host, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = conn.Close()
+		}
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer func() {
+			_ = conn.SetDeadline(time.Time{})
+		}()
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName: proxyURL.Hostname(),
+			MinVersion: tls.VersionTLS12,
+		})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, fmt.Errorf("cannot connect to proxy '%s': %w", proxyURL.Host, err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if u := proxyURL.User; u != nil {
+		password, _ := u.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+
+			base64.StdEncoding.EncodeToString([]byte(u.Username()+":"+password)),
+		)
+	}
+	if err = req.Write(conn); err != nil {
+		return nil, fmt.Errorf("cannot send CONNECT request to proxy '%s': %w", proxyURL.Host, err)
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CONNECT response from proxy '%s': %w", proxyURL.Host, err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy '%s' refused CONNECT to '%s': %s", proxyURL.Host, addr, resp.Status)
+	}
+	if r.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: r}, nil
+	}
+
+	return conn, nil
+}
+
+// bufferedConn returns data read ahead from the proxy before reading from the connection.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "github.com/yandex-cloud/go-genproto/yandex/cloud/iam/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestGRPCTransportProxy(t *testing.T) {
+	s := StubTokenService{
+		OnCreate: func(ctx context.Context, req *v1.CreateIamTokenRequest) (*v1.CreateIamTokenResponse, error) {
+			return &v1.CreateIamTokenResponse{IamToken: "foo", ExpiresAt: timestamppb.Now()}, nil
+		},
+	}
+	addr, stop, err := s.ListenAndServe()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, stop())
+	}()
+
+	var tunnels int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+
+			return
+		}
+		atomic.AddInt32(&tunnels, 1)
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			_ = upstream.Close()
+
+			return
+		}
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			_ = upstream.Close()
+		}()
+		_, _ = io.Copy(conn, upstream)
+		_ = conn.Close()
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+	proxyURL.User = url.UserPassword("user", "pass")
+
+	t.Run("Proxy", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		c := &client{endpoint: addr.String()}
+		require.NoError(t, WithProxy(proxyURL.String())(c))
+		transport := c.grpcTransport()
+		transport.insecure = true
+
+		_, _, err := transport.CreateToken(ctx, "jwt")
+		require.NoError(t, err)
+		require.Equal(t, int32(1), atomic.LoadInt32(&tunnels))
+	})
+
+	t.Run("ProxyAuthRequired", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		c := &client{endpoint: addr.String()}
+		require.NoError(t, WithProxy(proxy.URL)(c))
+		transport := c.grpcTransport()
+		transport.insecure = true
+
+		_, _, err := transport.CreateToken(ctx, "jwt")
+		require.Error(t, err)
+	})
+
+	t.Run("ContextDialer", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var dials int32
+		c := &client{endpoint: "iam.test:443"}
+		require.NoError(t, WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+
+			return (&net.Dialer{}).DialContext(ctx, "tcp", addr.String())
+		})(c))
+		transport := c.grpcTransport()
+		transport.insecure = true
+
+		_, _, err := transport.CreateToken(ctx, "jwt")
+		require.NoError(t, err)
+		require.Equal(t, int32(1), atomic.LoadInt32(&dials))
+	})
+
+	require.Error(t, WithProxy("ftp://proxy")(&client{}))
+}
+
+func TestProxyFromEnvironment(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://proxy.test:3128")
+	t.Setenv("NO_PROXY", "internal.test")
+
+	u, err := proxyFromEnvironment("iam.api.cloud.yandex.net:443")
+	require.NoError(t, err)
+	require.NotNil(t, u)
+	require.Equal(t, "proxy.test:3128", u.Host)
+
+	u, err = proxyFromEnvironment("iam.internal.test:443")
+	require.NoError(t, err)
+	require.Nil(t, u)
+}
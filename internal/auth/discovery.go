@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/endpoint"
+)
+
+// Endpoint discovery parameters.
+const (
+	DefaultDiscoveryEndpoint = "api.cloud.yandex.net:443"
+	DefaultDiscoveryTTL      = time.Hour
+
+	// Identifiers of services in the ApiEndpointService list.
+	ServiceIAM = "iam"
+	ServiceYDB = "ydb"
+)
+
+// listAPIEndpoints returns addresses of services (by service id) from ApiEndpointService at t.endpoint.
+func (t *grpcTransport) listAPIEndpoints(ctx context.Context) (map[string]string, error) {
+	var handshake handshakeError
+	conn, err := t.conn(ctx, &handshake)
+	if err != nil {
+		return nil, handshake.wrap(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	var (
+		client    = endpoint.NewApiEndpointServiceClient(conn)
+		endpoints = make(map[string]string)
+		pageToken string
+	)
+	for {
+		res, err := client.List(ctx, &endpoint.ListApiEndpointsRequest{PageToken: pageToken})
+		if err != nil {
+			return nil, handshake.wrap(err)
+		}
+		for _, e := range res.GetEndpoints() {
+			endpoints[e.GetId()] = e.GetAddress()
+		}
+		pageToken = res.GetNextPageToken()
+		if pageToken == "" {
+			return endpoints, nil
+		}
+	}
+}
+
+// discoverEndpoint returns the address of service listed by ApiEndpointService.List at t.endpoint.
+func discoverEndpoint(ctx context.Context, t *grpcTransport, service string) (string, error) {
+	endpoints, err := t.listAPIEndpoints(ctx)
+	if err != nil {
+		return "", fmt.Errorf("iam: cannot discover endpoints at '%s': %w", t.endpoint, err)
+	}
+	address, ok := endpoints[service]
+	if !ok {
+		return "", fmt.Errorf("iam: endpoint of service '%s' is not listed at '%s'", service, t.endpoint)
+	}
+
+	return address, nil
+}
+
+// discoveryTransport returns transport to the discovery endpoint with the connection settings
+// of the client. Pins are not applied since they belong to the iam endpoint.
+func (c *client) discoveryTransport() *grpcTransport {
+	t := c.grpcTransport()
+	t.endpoint = c.discoveryEndpoint
+	t.pins = nil
+
+	return t
+}
+
+// discover resolves iam endpoint if endpoint discovery is enabled. The discovered endpoint is cached
+// by the client for DefaultDiscoveryTTL, if the next discovery fails the cached endpoint is kept.
+func (c *client) discover(ctx context.Context) error {
+	now := c.clock.Now()
+	if c.discoveryEndpoint == "" || now.Before(c.discoveryExpires) {
+		return nil
+	}
+	address, err := discoverEndpoint(ctx, c.discoveryTransport(), ServiceIAM)
+	if err != nil {
+		if !c.discoveryExpires.IsZero() {
+			return nil
+		}
+
+		return err
+	}
+	c.discoveryExpires = now.Add(DefaultDiscoveryTTL)
+	if address == c.endpoint && c.transport != nil {
+		return nil
+	}
+	c.endpoint = address
+	if c.origins["audience"] == "" {
+		c.audience = audienceForEndpoint(address)
+	}
+	c.transport = c.newTransport()
+
+	return nil
+}
+
+// DiscoverEndpoint returns the address of service ("iam", "ydb", etc.) listed by ApiEndpointService
+// at the discovery endpoint (DefaultDiscoveryEndpoint if not provided with WithEndpointDiscovery).
+// Connection parameters are taken from opts.
+func DiscoverEndpoint(ctx context.Context, service string, opts ...ClientOption) (string, error) {
+	c := &client{
+		certPool:          defaultCertPool(),
+		discoveryEndpoint: DefaultDiscoveryEndpoint,
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return "", err
+		}
+	}
+
+	return discoverEndpoint(ctx, c.discoveryTransport(), service)
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/endpoint"
+	v1 "github.com/yandex-cloud/go-genproto/yandex/cloud/iam/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type stubEndpointService struct {
+	endpoint.UnimplementedApiEndpointServiceServer
+
+	endpoints []*endpoint.ApiEndpoint
+	calls     int
+}
+
+func (s *stubEndpointService) List(ctx context.Context, req *endpoint.ListApiEndpointsRequest) (
+	*endpoint.ListApiEndpointsResponse, error,
+) {
+	s.calls++
+	// Return one endpoint per page to check paging.
+	i := 0
+	if req.GetPageToken() != "" {
+		i = 1
+	}
+	res := &endpoint.ListApiEndpointsResponse{Endpoints: s.endpoints[i : i+1]}
+	if i+1 < len(s.endpoints) {
+		res.NextPageToken = "next"
+	}
+
+	return res, nil
+}
+
+func TestEndpointDiscovery(t *testing.T) {
+	cert, certPool := selfSignedCert(t, "iam.test")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var audience []string
+	iam := StubTokenService{
+		OnCreate: func(ctx context.Context, req *v1.CreateIamTokenRequest) (*v1.CreateIamTokenResponse, error) {
+			var claims jwt.RegisteredClaims
+			_, _, err := jwt.NewParser().ParseUnverified(req.GetJwt(), &claims)
+			require.NoError(t, err)
+			audience = claims.Audience
+
+			return &v1.CreateIamTokenResponse{
+				IamToken:  "foo",
+				ExpiresAt: timestamppb.New(time.Now().Add(time.Hour)),
+			}, nil
+		},
+	}
+	iamAddr, stop, err := iam.ListenAndServe(grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, stop())
+	}()
+
+	endpoints := &stubEndpointService{
+		endpoints: []*endpoint.ApiEndpoint{
+			{Id: ServiceYDB, Address: "ydb.test:443"},
+			{Id: ServiceIAM, Address: iamAddr.String()},
+		},
+	}
+	ln, err := (&net.ListenConfig{}).Listen(context.Background(), "tcp", "127.0.0.1:")
+	require.NoError(t, err)
+	srv := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	endpoint.RegisterApiEndpointServiceServer(srv, endpoints)
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	defer srv.Stop()
+
+	opts := []ClientOption{
+		WithEndpointDiscovery(ln.Addr().String()),
+		WithCertPool(certPool),
+		WithServerName("iam.test"),
+	}
+	clock := &manualClock{now: time.Now()}
+	c, err := NewClient(append(opts, WithPrivateKey(key), WithKeyID("key-id"), WithIssuer("sa-id"), WithClock(clock))...)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token, err := c.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "foo", token)
+	require.Equal(t, iamAddr.String(), c.(*client).endpoint)
+	require.Equal(t, []string{audienceForEndpoint(iamAddr.String())}, audience)
+
+	require.Equal(t, 2, endpoints.calls, "one discovery of two pages")
+
+	// Discovered endpoints are cached by the client for DefaultDiscoveryTTL of its clock.
+	clock.now = clock.now.Add(DefaultDiscoveryTTL / 2)
+	_, err = c.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, endpoints.calls)
+	clock.now = clock.now.Add(DefaultDiscoveryTTL)
+	_, err = c.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 4, endpoints.calls)
+
+	// Endpoints discovered by one client are not shared with others.
+	address, err := DiscoverEndpoint(ctx, ServiceYDB, opts...)
+	require.NoError(t, err)
+	require.Equal(t, "ydb.test:443", address)
+	require.Equal(t, 6, endpoints.calls)
+
+	_, err = DiscoverEndpoint(ctx, "unknown", opts...)
+	require.Error(t, err)
+}
@@ -2,40 +2,120 @@ package auth
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
 	"time"
 
 	v1 "github.com/yandex-cloud/go-genproto/yandex/cloud/iam/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/ydb-platform/ydb-go-yc/internal/version"
 )
 
 type grpcTransport struct {
 	endpoint           string
 	certPool           *x509.CertPool
-	insecure           bool // Only for testing.
-	insecureSkipVerify bool // Accept any TLS certificate from server.
+	serverName         string // Overrides the host name used to verify the server certificate.
+	minTLSVersion      uint16
+	pins               [][sha256.Size]byte // SPKI hashes, one of them must be in the server chain.
+	clientCertificate  func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+	proxy              *url.URL          // Explicit proxy, HTTPS_PROXY and NO_PROXY are used if nil.
+	dialer             contextDialer     // Dials the proxy or the endpoint, net.Dialer is used if nil.
+	dialOptions        []grpc.DialOption // Applied after the options of the transport.
+	insecure           bool              // Only for testing.
+	insecureSkipVerify bool              // Accept any TLS certificate from server.
+}
+
+// handshakeError keeps the error of the TLS verification callbacks, which is otherwise
+// reduced by grpc to a status message.
+type handshakeError struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (h *handshakeError) set(err error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.err = err
+
+	return err
+}
+
+// wrap returns err wrapped with the handshake error, if any.
+func (h *handshakeError) wrap(err error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.err == nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: %v", h.err, err)
 }
 
 func (t *grpcTransport) CreateToken(ctx context.Context, jwt string) (string, time.Time, error) {
-	conn, err := t.conn(ctx)
+	return t.createToken(ctx, func(ctx context.Context, client v1.IamTokenServiceClient) (
+		*v1.CreateIamTokenResponse, error,
+	) {
+		return client.Create(ctx, &v1.CreateIamTokenRequest{
+			Identity: &v1.CreateIamTokenRequest_Jwt{
+				Jwt: jwt,
+			},
+		})
+	})
+}
+
+func (t *grpcTransport) CreateTokenFromOAuth(ctx context.Context, oauthToken string) (string, time.Time, error) {
+	return t.createToken(ctx, func(ctx context.Context, client v1.IamTokenServiceClient) (
+		*v1.CreateIamTokenResponse, error,
+	) {
+		return client.Create(ctx, &v1.CreateIamTokenRequest{
+			Identity: &v1.CreateIamTokenRequest_YandexPassportOauthToken{
+				YandexPassportOauthToken: oauthToken,
+			},
+		})
+	})
+}
+
+func (t *grpcTransport) CreateTokenForServiceAccount(
+	ctx context.Context, iamToken, serviceAccountID string,
+) (string, time.Time, error) {
+	return t.createToken(ctx, func(ctx context.Context, client v1.IamTokenServiceClient) (
+		*v1.CreateIamTokenResponse, error,
+	) {
+		return client.CreateForServiceAccount(
+			metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+iamToken),
+			&v1.CreateIamTokenForServiceAccountRequest{
+				ServiceAccountId: serviceAccountID,
+			},
+		)
+	})
+}
+
+// createToken connects to the endpoint and makes the create call of IamTokenService.
+func (t *grpcTransport) createToken(
+	ctx context.Context,
+	create func(context.Context, v1.IamTokenServiceClient) (*v1.CreateIamTokenResponse, error),
+) (string, time.Time, error) {
+	var handshake handshakeError
+	conn, err := t.conn(ctx, &handshake)
 	if err != nil {
-		return "", time.Time{}, err
+		return "", time.Time{}, handshake.wrap(err)
 	}
 	defer func() {
 		_ = conn.Close()
 	}()
 
-	client := v1.NewIamTokenServiceClient(conn)
-	res, err := client.Create(ctx, &v1.CreateIamTokenRequest{
-		Identity: &v1.CreateIamTokenRequest_Jwt{
-			Jwt: jwt,
-		},
-	})
+	res, err := create(ctx, v1.NewIamTokenServiceClient(conn))
 	if err != nil {
-		return "", time.Time{}, err
+		return "", time.Time{}, handshake.wrap(err)
 	}
 
 	expiresAt := res.GetExpiresAt()
@@ -46,30 +126,62 @@ func (t *grpcTransport) CreateToken(ctx context.Context, jwt string) (string, ti
 	), nil
 }
 
-func (t *grpcTransport) conn(ctx context.Context) (*grpc.ClientConn, error) {
+func (t *grpcTransport) tlsConfig(handshake *handshakeError) *tls.Config {
+	minVersion := t.minTLSVersion
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+	config := &tls.Config{
+		RootCAs:    t.certPool,
+		ServerName: t.serverName,
+		MinVersion: minVersion,
+	}
+	if t.insecureSkipVerify {
+		config = &tls.Config{
+			//nolint: gosec
+			InsecureSkipVerify: true,
+			MinVersion:         minVersion,
+		}
+	}
+	config.GetClientCertificate = t.clientCertificate
+	if len(t.pins) > 0 {
+		pins := t.pins
+		config.VerifyConnection = func(cs tls.ConnectionState) error {
+			return handshake.set(verifyPins(cs, pins))
+		}
+	}
+
+	return config
+}
+
+func userAgent() string {
+	return "ydb-go-yc/" + version.Version()
+}
+
+func (t *grpcTransport) conn(ctx context.Context, handshake *handshakeError) (*grpc.ClientConn, error) {
 	var opts []grpc.DialOption
-	switch {
-	case t.insecure:
+	if t.insecure {
 		opts = []grpc.DialOption{
 			grpc.WithTransportCredentials(insecure.NewCredentials()),
 		}
-	case t.insecureSkipVerify:
+	} else {
 		opts = []grpc.DialOption{
-			grpc.WithTransportCredentials(
-				credentials.NewTLS(&tls.Config{
-					//nolint: gosec
-					InsecureSkipVerify: true,
-				}),
-			),
-		}
-	case t.certPool != nil:
-		opts = []grpc.DialOption{
-			grpc.WithTransportCredentials(
-				credentials.NewClientTLSFromCert(t.certPool, ""),
-			),
+			grpc.WithTransportCredentials(credentials.NewTLS(t.tlsConfig(handshake))),
 		}
 	}
 
+	forward := t.dialer
+	if forward == nil {
+		forward = defaultDialer
+	}
+	opts = append(opts,
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dial(ctx, forward, t.proxy, addr)
+		}),
+		grpc.WithUserAgent(userAgent()),
+	)
+	opts = append(opts, t.dialOptions...)
+
 	//nolint:staticcheck,nolintlint // grpc.NewClient migration is out of scope for this change.
 	return grpc.DialContext(ctx, t.endpoint, opts...)
 }
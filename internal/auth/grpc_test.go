@@ -2,13 +2,26 @@ package auth
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
 	"fmt"
+	"math/big"
 	"net"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
 	v1 "github.com/yandex-cloud/go-genproto/yandex/cloud/iam/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -57,6 +70,254 @@ func TestGRPCCreateToken(t *testing.T) {
 	}
 }
 
+func TestGRPCTransportTLS(t *testing.T) {
+	cert, certPool := selfSignedCert(t, "iam.test")
+	s := StubTokenService{
+		OnCreate: func(ctx context.Context, req *v1.CreateIamTokenRequest) (*v1.CreateIamTokenResponse, error) {
+			return &v1.CreateIamTokenResponse{IamToken: "foo", ExpiresAt: timestamppb.Now()}, nil
+		},
+	}
+	addr, stop, err := s.ListenAndServe(grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, stop())
+	}()
+
+	for _, tt := range []struct {
+		name      string
+		transport grpcTransport
+		wantErr   bool
+	}{
+		{
+			name:      "DefaultCertPool",
+			transport: grpcTransport{certPool: defaultCertPool(), serverName: "iam.test"},
+			wantErr:   true,
+		},
+		{
+			name:      "ServerNameMismatch",
+			transport: grpcTransport{certPool: certPool},
+			wantErr:   true,
+		},
+		{
+			name:      "Verified",
+			transport: grpcTransport{certPool: certPool, serverName: "iam.test"},
+		},
+		{
+			name:      "MinTLSVersion",
+			transport: grpcTransport{certPool: certPool, serverName: "iam.test", minTLSVersion: tls.VersionTLS13},
+		},
+		{
+			name:      "InsecureSkipVerify",
+			transport: grpcTransport{insecureSkipVerify: true},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			tt.transport.endpoint = addr.String()
+			_, _, err := tt.transport.CreateToken(ctx, "jwt")
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGRPCTransportPinnedPublicKeys(t *testing.T) {
+	cert, certPool := selfSignedCert(t, "iam.test")
+	other, _ := selfSignedCert(t, "iam.test")
+	s := StubTokenService{
+		OnCreate: func(ctx context.Context, req *v1.CreateIamTokenRequest) (*v1.CreateIamTokenResponse, error) {
+			return &v1.CreateIamTokenResponse{IamToken: "foo", ExpiresAt: timestamppb.Now()}, nil
+		},
+	}
+	addr, stop, err := s.ListenAndServe(grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, stop())
+	}()
+
+	pin := func(c tls.Certificate) string {
+		hash := publicKeyPin(c.Leaf)
+
+		return "sha256/" + base64.StdEncoding.EncodeToString(hash[:])
+	}
+
+	for _, tt := range []struct {
+		name    string
+		pins    []string
+		backup  []string
+		wantErr error
+	}{
+		{name: "Match", pins: []string{pin(cert)}},
+		{name: "Mismatch", pins: []string{pin(other)}, wantErr: ErrPublicKeyPinMismatch},
+		{name: "BackupMatch", pins: []string{pin(other)}, backup: []string{pin(cert)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &client{
+				endpoint:   addr.String(),
+				certPool:   certPool,
+				serverName: "iam.test",
+			}
+			require.NoError(t, WithPinnedPublicKeys(tt.pins...)(c))
+			if len(tt.backup) > 0 {
+				require.NoError(t, WithBackupPinnedPublicKeys(tt.backup...)(c))
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			_, _, err := c.grpcTransport().CreateToken(ctx, "jwt")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	require.Error(t, WithPinnedPublicKeys("not a pin")(&client{}))
+	require.Error(t, WithPinnedPublicKeys()(&client{}))
+	require.Error(t, WithBackupPinnedPublicKeys()(&client{}))
+
+	// Failed pin options must not let the client fall back to unpinned connections.
+	_, err = NewClient(WithEndpoint(addr.String()), WithPinnedPublicKeys())
+	require.Error(t, err)
+	_, err = NewClient(WithEndpoint(addr.String()), WithBackupPinnedPublicKeys(pin(cert)))
+	require.Error(t, err)
+}
+
+func TestGRPCTransportClientCertificate(t *testing.T) {
+	serverCert, serverPool := selfSignedCert(t, "iam.test")
+	clientCert, clientPool := selfSignedCert(t, "client")
+	s := StubTokenService{
+		OnCreate: func(ctx context.Context, req *v1.CreateIamTokenRequest) (*v1.CreateIamTokenResponse, error) {
+			return &v1.CreateIamTokenResponse{IamToken: "foo", ExpiresAt: timestamppb.Now()}, nil
+		},
+	}
+	addr, stop, err := s.ListenAndServe(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientPool,
+		MinVersion:   tls.VersionTLS12,
+	})))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, stop())
+	}()
+
+	var (
+		dir      = t.TempDir()
+		certFile = filepath.Join(dir, "cert.pem")
+		keyFile  = filepath.Join(dir, "key.pem")
+	)
+	writeCertificate(t, clientCert.Certificate[0], clientCert.PrivateKey.(*ecdsa.PrivateKey), certFile, keyFile)
+
+	for _, tt := range []struct {
+		name    string
+		opt     ClientOption
+		wantErr bool
+	}{
+		{name: "NoCertificate", opt: WithSourceInfo("test"), wantErr: true},
+		{name: "Files", opt: WithClientCertificate(certFile, keyFile)},
+		{name: "Func", opt: WithClientCertificateFunc(func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &clientCert, nil
+		})},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &client{
+				endpoint:   addr.String(),
+				certPool:   serverPool,
+				serverName: "iam.test",
+			}
+			require.NoError(t, tt.opt(c))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			_, _, err := c.grpcTransport().CreateToken(ctx, "jwt")
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGRPCTransportDialOptions(t *testing.T) {
+	var userAgent string
+	s := StubTokenService{
+		OnCreate: func(ctx context.Context, req *v1.CreateIamTokenRequest) (*v1.CreateIamTokenResponse, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			userAgent = strings.Join(md.Get("user-agent"), ",")
+
+			return &v1.CreateIamTokenResponse{IamToken: "foo", ExpiresAt: timestamppb.Now()}, nil
+		},
+	}
+	addr, stop, err := s.ListenAndServe()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, stop())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gt := grpcTransport{endpoint: addr.String(), insecure: true}
+	_, _, err = gt.CreateToken(ctx, "jwt")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(userAgent, "ydb-go-yc/"), userAgent)
+
+	var methods []string
+	c := &client{endpoint: addr.String()}
+	require.NoError(t, WithGRPCDialOptions(
+		grpc.WithUserAgent("custom"),
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{},
+			cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+		) error {
+			methods = append(methods, method)
+
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+	)(c))
+	gt = *c.grpcTransport()
+	gt.insecure = true
+	_, _, err = gt.CreateToken(ctx, "jwt")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(userAgent, "custom"), userAgent)
+	require.Equal(t, []string{v1.IamTokenService_Create_FullMethodName}, methods)
+}
+
+// selfSignedCert makes a certificate for the provided host and a cert pool which trusts it.
+func selfSignedCert(t *testing.T, host string) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: host},
+		DNSNames:              []string{host},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, certPool
+}
+
 type StubTokenService struct {
 	v1.UnimplementedIamTokenServiceServer
 
@@ -65,7 +326,7 @@ type StubTokenService struct {
 		*v1.CreateIamTokenResponse, error)
 }
 
-func (s *StubTokenService) ListenAndServe() (
+func (s *StubTokenService) ListenAndServe(opts ...grpc.ServerOption) (
 	addr net.Addr,
 	stop func() error,
 	err error,
@@ -75,7 +336,7 @@ func (s *StubTokenService) ListenAndServe() (
 		return nil, nil, err
 	}
 
-	srv := grpc.NewServer()
+	srv := grpc.NewServer(opts...)
 	v1.RegisterIamTokenServiceServer(srv, s)
 
 	serve := make(chan error)
@@ -4,11 +4,16 @@ package auth
 import (
 	"context"
 	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"log"
+	"net"
+	"net/url"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -18,6 +23,9 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/jonboulle/clockwork"
 	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+	"google.golang.org/grpc"
+
+	"github.com/ydb-platform/ydb-go-yc/trace"
 )
 
 // Default client parameters.
@@ -30,6 +38,7 @@ const (
 var (
 	ErrServiceFileInvalid = errors.New("service account file is not valid")
 	ErrKeyCannotBeParsed  = errors.New("private key can not be parsed")
+	ErrConflictingOptions = errors.New("conflicting client options")
 )
 
 // createTokenError contains reason of token creation failure.
@@ -47,12 +56,62 @@ func (e *createTokenError) Unwrap() error {
 	return e.cause
 }
 
-type transport interface {
+// TokenExchanger exchanges the signed jwt for an iam token, see WithTransport.
+type TokenExchanger interface {
 	CreateToken(ctx context.Context, jwt string) (token string, expires time.Time, err error)
 }
 
+// TokenExchangerFunc is an adapter to use ordinary functions as TokenExchanger.
+type TokenExchangerFunc func(ctx context.Context, jwt string) (token string, expires time.Time, err error)
+
+func (f TokenExchangerFunc) CreateToken(ctx context.Context, jwt string) (string, time.Time, error) {
+	return f(ctx, jwt)
+}
+
+// oauthExchanger exchanges the OAuth token of Yandex account for an iam token.
+type oauthExchanger interface {
+	CreateTokenFromOAuth(ctx context.Context, oauthToken string) (token string, expires time.Time, err error)
+}
+
+// ImpersonationExchanger issues tokens of the service account to the holder of the iam token with
+// IamTokenService.CreateForServiceAccount. TokenExchanger of WithTransport used for impersonation
+// (WithImpersonatedServiceAccount, NewImpersonator) must implement it.
+type ImpersonationExchanger interface {
+	CreateTokenForServiceAccount(ctx context.Context, iamToken, serviceAccountID string) (
+		token string, expires time.Time, err error,
+	)
+}
+
 type ClientOption func(*client) error
 
+// Clock provides the current time to the client, see WithClock. clockwork.Clock implements it.
+//
+// If the clock also implements After(time.Duration) <-chan time.Time (as clockwork.Clock does),
+// the retry backoff of WithRetry is waited with it.
+type Clock interface {
+	Now() time.Time
+}
+
+// timerClock is a Clock which provides timers.
+type timerClock interface {
+	After(d time.Duration) <-chan time.Time
+}
+
+// claim records option as the source of field. It returns ErrConflictingOptions if field was
+// already set to a different value by another option. Repeating the same option is allowed and
+// the last one wins, as before.
+func (c *client) claim(field, option string, same bool) error {
+	if prev, ok := c.origins[field]; ok && prev != option && !same {
+		return fmt.Errorf("%w: %s and %s both set %s", ErrConflictingOptions, prev, option, field)
+	}
+	if c.origins == nil {
+		c.origins = make(map[string]string)
+	}
+	c.origins[field] = option
+
+	return nil
+}
+
 // WithFallbackCredentials makes fallback credentials if primary credentials are failed
 func WithFallbackCredentials(fallback credentials.Credentials) ClientOption {
 	return func(c *client) error {
@@ -65,7 +124,62 @@ func WithFallbackCredentials(fallback credentials.Credentials) ClientOption {
 // WithEndpoint set provided endpoint.
 func WithEndpoint(endpoint string) ClientOption {
 	return func(c *client) error {
+		if err := c.claim("endpoint", "WithEndpoint", c.endpoint == endpoint); err != nil {
+			return err
+		}
 		c.endpoint = endpoint
+		c.endpoints = nil
+
+		return nil
+	}
+}
+
+// WithEndpoints set several iam endpoints. Endpoints are tried in the order defined by WithBalancing
+// and an endpoint is ejected for a cooldown after consecutive failures (see WithEndpointCircuitBreaker).
+func WithEndpoints(endpoints ...string) ClientOption {
+	return func(c *client) error {
+		if len(endpoints) == 0 {
+			return fmt.Errorf("iam: at least one endpoint required")
+		}
+		if err := c.claim("endpoint", "WithEndpoints", false); err != nil {
+			return err
+		}
+		c.endpoint = endpoints[0]
+		c.endpoints = append([]string{}, endpoints...)
+
+		return nil
+	}
+}
+
+// WithBalancing set the order in which endpoints provided with WithEndpoints are tried.
+// Defaults to BalancingPriority.
+func WithBalancing(balancing Balancing) ClientOption {
+	return func(c *client) error {
+		c.balancing = balancing
+
+		return nil
+	}
+}
+
+// WithEndpointCircuitBreaker set circuit breaker parameters of endpoints provided with WithEndpoints:
+// an endpoint is ejected for cooldown after maxFailures consecutive failures.
+// Defaults to DefaultEndpointMaxFailures and DefaultEndpointCooldown.
+func WithEndpointCircuitBreaker(maxFailures int, cooldown time.Duration) ClientOption {
+	return func(c *client) error {
+		if maxFailures < 1 {
+			return fmt.Errorf("iam: max failures must be positive, got %d", maxFailures)
+		}
+		c.maxFailures = maxFailures
+		c.cooldown = cooldown
+
+		return nil
+	}
+}
+
+// WithTrace set trace of the client events. Several traces are composed.
+func WithTrace(t trace.Trace) ClientOption {
+	return func(c *client) error {
+		c.trace = c.trace.Compose(t)
 
 		return nil
 	}
@@ -74,7 +188,72 @@ func WithEndpoint(endpoint string) ClientOption {
 // WithDefaultEndpoint set endpoint with default value.
 func WithDefaultEndpoint() ClientOption {
 	return func(c *client) error {
+		if err := c.claim("endpoint", "WithDefaultEndpoint", c.endpoint == DefaultEndpoint); err != nil {
+			return err
+		}
 		c.endpoint = DefaultEndpoint
+		c.endpoints = nil
+
+		return nil
+	}
+}
+
+// WithInstallation set endpoint, audience and root certificates of the provided installation.
+// Root certificates are system ones extended with the installation CA and certificates of
+// WithCertPoolFile, they can not be combined with WithCertPool and WithSystemCertPool.
+func WithInstallation(installation Installation) ClientOption {
+	return func(c *client) error {
+		option := "WithInstallation(" + installation.Name + ")"
+		if err := c.claim("endpoint", option, c.endpoint == installation.Endpoint); err != nil {
+			return err
+		}
+		if err := c.claim("audience", option, c.audience == installation.Audience); err != nil {
+			return err
+		}
+		if len(installation.CA) > 0 {
+			if err := c.claim("cert pool", option, false); err != nil {
+				return err
+			}
+			// The pool is built anew, so pools shared with other clients are never modified.
+			certPool := systemCertPool()
+			if !certPool.AppendCertsFromPEM(installation.CA) {
+				return fmt.Errorf("cannot append certificates of installation '%s' to certificates pool", installation.Name)
+			}
+			c.certPool = c.withCertFiles(certPool)
+		}
+		c.endpoint = installation.Endpoint
+		c.endpoints = nil
+		c.audience = installation.Audience
+
+		return nil
+	}
+}
+
+// WithInstallationName set endpoint, audience and root certificates of the known installation
+// ("yandex-cloud", "yandex-cloud-kz").
+func WithInstallationName(name string) ClientOption {
+	return func(c *client) error {
+		installation, ok := LookupInstallation(name)
+		if !ok {
+			return fmt.Errorf("unknown installation '%s'", name)
+		}
+
+		return WithInstallation(installation)(c)
+	}
+}
+
+// WithEndpointDiscovery makes client resolve iam endpoint with ApiEndpointService.List at discoveryEndpoint
+// (DefaultDiscoveryEndpoint if empty) on the first Token call. Discovered endpoints are cached for
+// DefaultDiscoveryTTL by the client. Unless audience is provided, it follows the discovered endpoint.
+func WithEndpointDiscovery(discoveryEndpoint string) ClientOption {
+	return func(c *client) error {
+		if discoveryEndpoint == "" {
+			discoveryEndpoint = DefaultDiscoveryEndpoint
+		}
+		if err := c.claim("endpoint", "WithEndpointDiscovery", false); err != nil {
+			return err
+		}
+		c.discoveryEndpoint = discoveryEndpoint
 
 		return nil
 	}
@@ -89,18 +268,30 @@ func WithSourceInfo(sourceInfo string) ClientOption {
 	}
 }
 
-// WithCertPool set provided certPool.
+// WithCertPool set provided certPool. The pool is not modified, so it can not be combined with
+// WithCertPoolFile.
 func WithCertPool(certPool *x509.CertPool) ClientOption {
 	return func(c *client) error {
+		if err := c.claim("cert pool", "WithCertPool", false); err != nil {
+			return err
+		}
+		if c.certFileOrigin != "" {
+			return errCertPoolFileConflict
+		}
 		c.certPool = certPool
 
 		return nil
 	}
 }
 
-// WithCertPoolFile try set root certPool from provided cert file path.
+// WithCertPoolFile appends certificates from provided cert file path to the root cert pool: the default
+// one, the one of WithSystemCertPool or WithInstallation regardless of the order of options.
+// The pool of WithCertPool is not modified, so the options can not be combined.
 func WithCertPoolFile(caFile string) ClientOption {
 	return func(c *client) error {
+		if c.origins["cert pool"] == "WithCertPool" {
+			return errCertPoolFileConflict
+		}
 		if len(caFile) > 0 && caFile[0] == '~' {
 			usr, err := user.Current()
 			if err != nil {
@@ -112,9 +303,16 @@ func WithCertPoolFile(caFile string) ClientOption {
 		if err != nil {
 			return err
 		}
-		if !c.certPool.AppendCertsFromPEM(bytes) {
+		if !x509.NewCertPool().AppendCertsFromPEM(bytes) {
 			return fmt.Errorf("cannot append certificates from file '%s' to certificates pool", caFile)
 		}
+		// The pool is owned by the client: the default, system or installation one.
+		if c.certPool == nil {
+			c.certPool = x509.NewCertPool()
+		}
+		c.certPool.AppendCertsFromPEM(bytes)
+		c.certFiles = append(c.certFiles, bytes)
+		c.certFileOrigin = "WithCertPoolFile"
 
 		return nil
 	}
@@ -123,21 +321,177 @@ func WithCertPoolFile(caFile string) ClientOption {
 // WithSystemCertPool try set certPool with system root certificates.
 func WithSystemCertPool() ClientOption {
 	return func(c *client) error {
-		var err error
-		c.certPool, err = x509.SystemCertPool()
+		if err := c.claim("cert pool", "WithSystemCertPool", false); err != nil {
+			return err
+		}
+		certPool, err := x509.SystemCertPool()
+		if err != nil {
+			return err
+		}
+		c.certPool = c.withCertFiles(certPool)
 
-		return err
+		return nil
+	}
+}
+
+var errCertPoolFileConflict = fmt.Errorf("%w: WithCertPool and WithCertPoolFile (the provided pool is not modified)",
+	ErrConflictingOptions,
+)
+
+// withCertFiles appends certificates of WithCertPoolFile to certPool owned by the client.
+func (c *client) withCertFiles(certPool *x509.CertPool) *x509.CertPool {
+	for _, ca := range c.certFiles {
+		certPool.AppendCertsFromPEM(ca)
+	}
+
+	return certPool
+}
+
+// WithServerName overrides the host name used to verify the iam server certificate.
+// By default, the host of the endpoint is used.
+func WithServerName(serverName string) ClientOption {
+	return func(c *client) error {
+		c.serverName = serverName
+
+		return nil
+	}
+}
+
+// WithMinTLSVersion set minimal TLS version (tls.VersionTLS12, tls.VersionTLS13, etc.) accepted
+// from the iam server. Defaults to tls.VersionTLS12.
+func WithMinTLSVersion(version uint16) ClientOption {
+	return func(c *client) error {
+		if version < tls.VersionTLS12 {
+			return fmt.Errorf("TLS version %#04x is not supported, use tls.VersionTLS12 or newer", version)
+		}
+		c.minTLSVersion = version
+
+		return nil
+	}
+}
+
+// WithPinnedPublicKeys pins the iam server certificate chain by base64 encoded SHA-256 hashes of
+// SubjectPublicKeyInfo (optionally prefixed with "sha256/"). The pins are checked in addition to
+// the normal certificate verification: the connection fails with ErrPublicKeyPinMismatch unless
+// at least one certificate of the verified chain matches one of pinned or backup pinned keys.
+//
+// At least one hash is required, empty list is an error rather than disabled pinning.
+func WithPinnedPublicKeys(hashes ...string) ClientOption {
+	return func(c *client) error {
+		if len(hashes) == 0 {
+			return fmt.Errorf("iam: at least one pinned public key required")
+		}
+		pins, err := parsePins(hashes)
+		if err != nil {
+			return err
+		}
+		c.pins = pins
+
+		return nil
+	}
+}
+
+// WithBackupPinnedPublicKeys set backup pins accepted along with WithPinnedPublicKeys, e.g. keys
+// of the next CA during CA rotation. At least one hash is required.
+func WithBackupPinnedPublicKeys(hashes ...string) ClientOption {
+	return func(c *client) error {
+		if len(hashes) == 0 {
+			return fmt.Errorf("iam: at least one backup pinned public key required")
+		}
+		pins, err := parsePins(hashes)
+		if err != nil {
+			return err
+		}
+		c.backupPins = pins
+
+		return nil
+	}
+}
+
+// WithClientCertificate set client certificate presented to the iam server from provided PEM files.
+// The files are reloaded on the next connection after they change, so certificates can be rotated
+// without restarting the application.
+func WithClientCertificate(certFile, keyFile string) ClientOption {
+	return func(c *client) error {
+		if err := c.claim("client certificate", "WithClientCertificate", false); err != nil {
+			return err
+		}
+		f, err := newCertificateFile(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		c.clientCertificate = f.GetClientCertificate
+
+		return nil
+	}
+}
+
+// WithClientCertificateFunc set callback which returns client certificate presented to the iam server.
+// The callback is called on each TLS handshake.
+func WithClientCertificateFunc(
+	getCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error),
+) ClientOption {
+	return func(c *client) error {
+		if err := c.claim("client certificate", "WithClientCertificateFunc", false); err != nil {
+			return err
+		}
+		c.clientCertificate = getCertificate
+
+		return nil
+	}
+}
+
+// WithProxy set proxy used to connect to the iam server. Supported schemes are http and https
+// (HTTP CONNECT tunnel), socks5 and socks5h. Credentials may be provided in the url user info.
+//
+// Without this option the proxy is taken from HTTPS_PROXY and NO_PROXY environment variables.
+func WithProxy(proxyURL string) ClientOption {
+	return func(c *client) error {
+		u, err := parseProxyURL(proxyURL)
+		if err != nil {
+			return err
+		}
+		c.proxy = u
+
+		return nil
+	}
+}
+
+// WithContextDialer set dialer used to connect to the iam server or to the proxy.
+func WithContextDialer(dialer func(ctx context.Context, addr string) (net.Conn, error)) ClientOption {
+	return func(c *client) error {
+		c.dialer = dialer
+
+		return nil
+	}
+}
+
+// WithGRPCDialOptions appends options to the options of the iam connection (keepalive parameters,
+// interceptors, user agent, etc.). The options are applied after the transport security and dialer
+// options of the client, so they take precedence.
+func WithGRPCDialOptions(opts ...grpc.DialOption) ClientOption {
+	return func(c *client) error {
+		c.dialOptions = append(c.dialOptions, opts...)
+
+		return nil
 	}
 }
 
 // WithInsecureSkipVerify set insecureSkipVerify to true which force client accepts any TLS certificate
 // presented by the iam server and any host name in that certificate.
 //
-// If insecureSkipVerify is set, then certPool field is not used.
+// If insecureSkipVerify is set, then certPool field is not used. NewClient reports disabled
+// verification with trace.Trace.OnInsecureSkipVerify (see WithTrace), or logs a warning with
+// the standard logger if the trace is not set.
 //
 // This should be used only for testing purposes.
 func WithInsecureSkipVerify(insecure bool) ClientOption {
 	return func(c *client) error {
+		if insecure {
+			c.insecureOrigin = "WithInsecureSkipVerify(true)"
+		} else {
+			c.insecureOrigin = ""
+		}
 		c.insecureSkipVerify = insecure
 
 		return nil
@@ -147,6 +501,9 @@ func WithInsecureSkipVerify(insecure bool) ClientOption {
 // WithKeyID set provided keyID.
 func WithKeyID(keyID string) ClientOption {
 	return func(c *client) error {
+		if err := c.claim("key id", "WithKeyID", c.keyID == keyID); err != nil {
+			return err
+		}
 		c.keyID = keyID
 
 		return nil
@@ -156,6 +513,9 @@ func WithKeyID(keyID string) ClientOption {
 // WithIssuer set provided issuer.
 func WithIssuer(issuer string) ClientOption {
 	return func(c *client) error {
+		if err := c.claim("issuer", "WithIssuer", c.issuer == issuer); err != nil {
+			return err
+		}
 		c.issuer = issuer
 
 		return nil
@@ -171,9 +531,97 @@ func WithTokenTTL(tokenTTL time.Duration) ClientOption {
 	}
 }
 
+// WithTransport makes client exchange jwt for iam tokens with exchanger instead of IamTokenService.Create
+// at the iam endpoint, e.g. through a gateway or a stub in tests. Jwt signing and token caching are
+// performed by the client as usual, connection options (WithEndpoint, WithCertPool, etc.) are not used.
+//
+// Impersonation with WithImpersonatedServiceAccount or NewImpersonator is made with exchanger as well,
+// so it must implement ImpersonationExchanger then.
+func WithTransport(exchanger TokenExchanger) ClientOption {
+	return func(c *client) error {
+		if exchanger == nil {
+			return fmt.Errorf("iam: token exchanger is nil")
+		}
+		c.exchanger = exchanger
+
+		return nil
+	}
+}
+
+// WithClock set the clock used to issue jwt, to expire cached tokens and to wait retry backoff.
+func WithClock(clock Clock) ClientOption {
+	return func(c *client) error {
+		if clock == nil {
+			return fmt.Errorf("iam: clock is nil")
+		}
+		c.clock = clock
+
+		return nil
+	}
+}
+
+// WithRetry makes client retry token creation up to attempts times (including the first one) if the
+// iam endpoint fails with unavailability, timeout, etc. The n-th retry is made after n*backoff.
+func WithRetry(attempts int, backoff time.Duration) ClientOption {
+	return func(c *client) error {
+		if attempts < 1 {
+			return fmt.Errorf("iam: retry attempts must be positive, got %d", attempts)
+		}
+		c.retryAttempts = attempts
+		c.retryBackoff = backoff
+
+		return nil
+	}
+}
+
+// WithRefreshRatio set the part of the token lifetime after which the cached token is refreshed.
+// Defaults to 0.5.
+func WithRefreshRatio(ratio float64) ClientOption {
+	return func(c *client) error {
+		if ratio <= 0 || ratio >= 1 {
+			return fmt.Errorf("iam: refresh ratio must be in (0, 1), got %v", ratio)
+		}
+		c.refreshRatio = ratio
+
+		return nil
+	}
+}
+
+// WithOAuthToken makes client exchange the OAuth token of Yandex account for iam tokens instead of
+// the jwt signed by the service account key.
+//
+// Do not mix this option with service account key options.
+func WithOAuthToken(oauthToken string) ClientOption {
+	return func(c *client) error {
+		if oauthToken == "" {
+			return fmt.Errorf("iam: OAuth token is empty")
+		}
+		c.oauthToken = oauthToken
+
+		return nil
+	}
+}
+
+// WithImpersonatedServiceAccount makes client return tokens of the service account serviceAccountID
+// issued by IamTokenService.CreateForServiceAccount to the identity of the client. If the client can not
+// be created, the service account is impersonated by credentials of WithFallbackCredentials.
+func WithImpersonatedServiceAccount(serviceAccountID string) ClientOption {
+	return func(c *client) error {
+		if serviceAccountID == "" {
+			return fmt.Errorf("iam: impersonated service account id is empty")
+		}
+		c.impersonate = serviceAccountID
+
+		return nil
+	}
+}
+
 // WithAudience set provided audience.
 func WithAudience(audience string) ClientOption {
 	return func(c *client) error {
+		if err := c.claim("audience", "WithAudience", c.audience == audience); err != nil {
+			return err
+		}
 		c.audience = audience
 
 		return nil
@@ -183,6 +631,9 @@ func WithAudience(audience string) ClientOption {
 // WithPrivateKey set provided private key.
 func WithPrivateKey(key *rsa.PrivateKey) ClientOption {
 	return func(c *client) error {
+		if err := c.claim("private key", "WithPrivateKey", c.key != nil && c.key.Equal(key)); err != nil {
+			return err
+		}
 		c.key = key
 
 		return nil
@@ -200,6 +651,9 @@ func WithPrivateKeyFile(path string) ClientOption {
 		if err != nil {
 			return err
 		}
+		if err := c.claim("private key", "WithPrivateKeyFile", c.key != nil && c.key.Equal(key)); err != nil {
+			return err
+		}
 		c.key = key
 
 		return nil
@@ -223,7 +677,7 @@ func WithServiceFile(path string) ClientOption {
 			return err
 		}
 
-		return parseAndApplyServiceAccountKeyData(c, data)
+		return parseAndApplyServiceAccountKeyData(c, data, "WithServiceFile")
 	}
 }
 
@@ -231,86 +685,240 @@ func WithServiceFile(path string) ClientOption {
 //
 // Do not mix this option with WithKeyID, WithIssuer and key options (WithPrivateKey, WithPrivateKeyFile, etc).
 func WithServiceKey(key string) ClientOption {
-	return func(c *client) error { return parseAndApplyServiceAccountKeyData(c, []byte(key)) }
+	return func(c *client) error { return parseAndApplyServiceAccountKeyData(c, []byte(key), "WithServiceKey") }
+}
+
+// serviceAccountKey is the service account key file created by `yc iam key create`.
+type serviceAccountKey struct {
+	ID               string `json:"id"`
+	ServiceAccountID string `json:"service_account_id"` //nolint:tagliatelle // Yandex Cloud SA key JSON format.
+	PrivateKey       string `json:"private_key"`        //nolint:tagliatelle // Yandex Cloud SA key JSON format.
+	Endpoint         string `json:"endpoint,omitempty"`
+	PublicKey        string `json:"public_key,omitempty"`    //nolint:tagliatelle // Yandex Cloud SA key JSON format.
+	KeyAlgorithm     string `json:"key_algorithm,omitempty"` //nolint:tagliatelle // Yandex Cloud SA key JSON format.
+	CreatedAt        string `json:"created_at,omitempty"`    //nolint:tagliatelle // Yandex Cloud SA key JSON format.
+}
+
+// parseServiceAccountKey parses service account key data. On error, the fields parsed so far are
+// returned for diagnostics.
+func parseServiceAccountKey(data []byte) (info serviceAccountKey, key *rsa.PrivateKey, err error) {
+	if err = json.Unmarshal(data, &info); err != nil {
+		return info, nil, err
+	}
+	if info.ID == "" || info.ServiceAccountID == "" || info.PrivateKey == "" {
+		return info, nil, ErrServiceFileInvalid
+	}
+	key, err = parsePrivateKey([]byte(info.PrivateKey))
+
+	return info, key, err
 }
 
 // parseAndApplyServiceAccountKeyData set key, keyID, issuer from provided service account data key,
-// or form service account file path.
+// or form service account file path. Option is the name of the calling option used in conflict errors.
 //
 //	Do not mix this option with WithKeyID, WithIssuer and key options (WithPrivateKey, WithPrivateKeyFile, etc).
-func parseAndApplyServiceAccountKeyData(c *client, data []byte) error {
-	type keyFile struct {
-		ID               string `json:"id"`
-		ServiceAccountID string `json:"service_account_id"` //nolint:tagliatelle // Yandex Cloud SA key JSON format.
-		PrivateKey       string `json:"private_key"`        //nolint:tagliatelle // Yandex Cloud SA key JSON format.
-		Endpoint         string `json:"endpoint,omitempty"`
-	}
-	var info keyFile
-	if err := json.Unmarshal(data, &info); err != nil {
+func parseAndApplyServiceAccountKeyData(c *client, data []byte, option string) error {
+	info, key, err := parseServiceAccountKey(data)
+	if err != nil {
 		return err
 	}
-	if info.ID == "" || info.ServiceAccountID == "" || info.PrivateKey == "" {
-		return ErrServiceFileInvalid
+	if err := c.claim("private key", option, c.key != nil && c.key.Equal(key)); err != nil {
+		return err
 	}
-
-	key, err := parsePrivateKey([]byte(info.PrivateKey))
-	if err != nil {
+	if err := c.claim("key id", option, c.keyID == info.ID); err != nil {
+		return err
+	}
+	if err := c.claim("issuer", option, c.issuer == info.ServiceAccountID); err != nil {
 		return err
 	}
+	if info.Endpoint != "" {
+		if err := c.claim("endpoint", option, c.endpoint == info.Endpoint); err != nil {
+			return err
+		}
+	}
 	c.key = key
 	c.keyID = info.ID
 	c.issuer = info.ServiceAccountID
 	if info.Endpoint != "" {
 		c.endpoint = info.Endpoint
+		c.endpoints = nil
 	}
 
 	return nil
 }
 
+// defaultCertPool returns system root certificates extended with Yandex Cloud root certificates.
+func defaultCertPool() *x509.CertPool {
+	certPool := systemCertPool()
+	certPool.AppendCertsFromPEM(ycPEM)
+
+	return certPool
+}
+
+// systemCertPool returns a new pool of system root certificates, or an empty pool if they are not available.
+func systemCertPool() *x509.CertPool {
+	certPool, err := x509.SystemCertPool()
+	if err != nil {
+		return x509.NewCertPool()
+	}
+
+	return certPool
+}
+
 // NewClient creates IAM (jwt) authorized client from provided ClientOptions list.
 //
+// The iam server certificate is verified against system root certificates and Yandex Cloud
+// root certificates unless other cert pool options are provided.
+//
 // To create successfully at least one of endpoint options must be provided.
 func NewClient(opts ...ClientOption) (_ credentials.Credentials, err error) {
-	var (
-		certPool *x509.CertPool
-		issues   []error
-	)
-	certPool, err = x509.SystemCertPool()
-	if err != nil {
-		certPool = x509.NewCertPool()
-	}
+	var issues []error
 
 	c := &client{
-		endpoint:           DefaultEndpoint,
-		certPool:           certPool,
-		insecureSkipVerify: true,
-		tokenTTL:           DefaultTokenTTL,
-		audience:           DefaultAudience,
-		clock:              clockwork.NewRealClock(),
+		endpoint: DefaultEndpoint,
+		certPool: defaultCertPool(),
+		tokenTTL: DefaultTokenTTL,
+		audience: DefaultAudience,
+		clock:    clockwork.NewRealClock(),
 	}
 
 	for _, opt := range opts {
 		err = opt(c)
+		if errors.Is(err, ErrConflictingOptions) {
+			return nil, fmt.Errorf("cannot create IAM client: %w", err)
+		}
 		if err != nil {
 			issues = append(issues, err)
 		}
 	}
 
+	if err = c.validate(); err != nil {
+		return nil, fmt.Errorf("cannot create IAM client: %w", err)
+	}
+
+	// Endpoint from the service account key defines the installation, so the audience must match it.
+	if origin := c.origins["endpoint"]; c.origins["audience"] == "" &&
+		(origin == "WithServiceFile" || origin == "WithServiceKey") {
+		c.audience = audienceForEndpoint(c.endpoint)
+	}
+
+	var base credentials.Credentials = c
 	if len(issues) > 0 {
-		if c.fallback != nil {
+		if c.fallback == nil {
+			return nil, fmt.Errorf("cannot create IAM client: %v", issues)
+		}
+		if c.impersonate == "" {
 			return c.fallback, nil
 		}
+		// The service account is impersonated by fallback credentials as well.
+		base = c.fallback
+	}
+
+	if c.insecureSkipVerify {
+		if c.trace.OnInsecureSkipVerify == nil {
+			log.Printf("ydb-go-yc: WARNING: TLS certificate verification of iam endpoint %q is disabled "+
+				"by WithInsecureSkipVerify(true), use it only for testing", c.endpoint,
+			)
+		}
+		trace.TraceOnInsecureSkipVerify(c.trace, c.endpoint)
+	}
+
+	c.transport = c.exchanger
+	if c.transport == nil {
+		c.transport = c.newTransport()
+	}
+
+	if c.impersonate != "" {
+		return newImpersonator(base, c).ServiceAccount(c.impersonate), nil
+	}
+
+	return c, nil
+}
 
-		return nil, fmt.Errorf("cannot create IAM client: %v", issues)
+// newTransport returns grpc transport to the endpoint, or failover transport if several
+// endpoints are provided.
+func (c *client) newTransport() TokenExchanger {
+	if len(c.endpoints) < 2 {
+		return c.grpcTransport()
 	}
+	m := &multiTransport{
+		balancing:   c.balancing,
+		maxFailures: c.maxFailures,
+		cooldown:    c.cooldown,
+		clock:       c.clock,
+		trace:       c.trace,
+	}
+	if m.maxFailures == 0 {
+		m.maxFailures = DefaultEndpointMaxFailures
+		m.cooldown = DefaultEndpointCooldown
+	}
+	for _, endpoint := range c.endpoints {
+		t := c.grpcTransport()
+		t.endpoint = endpoint
+		m.endpoints = append(m.endpoints, &endpointState{
+			endpoint:  endpoint,
+			transport: t,
+		})
+	}
+
+	return m
+}
 
-	c.transport = &grpcTransport{
+func (c *client) grpcTransport() *grpcTransport {
+	return &grpcTransport{
 		endpoint:           c.endpoint,
 		certPool:           c.certPool,
+		serverName:         c.serverName,
+		minTLSVersion:      c.minTLSVersion,
+		pins:               c.allPins(),
+		clientCertificate:  c.clientCertificate,
+		proxy:              c.proxy,
+		dialer:             c.dialer,
+		dialOptions:        c.dialOptions,
 		insecureSkipVerify: c.insecureSkipVerify,
 	}
+}
 
-	return c, nil
+func (c *client) allPins() [][sha256.Size]byte {
+	if len(c.pins) == 0 {
+		return nil
+	}
+
+	return append(append([][sha256.Size]byte{}, c.pins...), c.backupPins...)
+}
+
+// validate checks combinations of options which can not be detected while applying a single option.
+func (c *client) validate() error {
+	if len(c.backupPins) > 0 && len(c.pins) == 0 {
+		return fmt.Errorf("WithBackupPinnedPublicKeys requires WithPinnedPublicKeys")
+	}
+	if c.oauthToken != "" && c.key != nil {
+		return fmt.Errorf("%w: WithOAuthToken and service account key (%s)",
+			ErrConflictingOptions, c.origins["private key"],
+		)
+	}
+	if _, ok := c.exchanger.(ImpersonationExchanger); c.exchanger != nil && c.impersonate != "" && !ok {
+		return fmt.Errorf("WithImpersonatedServiceAccount requires WithTransport exchanger which implements " +
+			"ImpersonationExchanger")
+	}
+	if c.exchanger != nil && c.discoveryEndpoint != "" {
+		return fmt.Errorf("%w: WithTransport and WithEndpointDiscovery (discovered endpoint is not used by the transport)",
+			ErrConflictingOptions,
+		)
+	}
+	if c.insecureSkipVerify && c.insecureOrigin != "" {
+		certOrigin := c.origins["cert pool"]
+		if certOrigin == "" {
+			certOrigin = c.certFileOrigin
+		}
+		if certOrigin != "" {
+			return fmt.Errorf("%w: %s and %s (cert pool is not used when verification is skipped)",
+				ErrConflictingOptions, c.insecureOrigin, certOrigin,
+			)
+		}
+	}
+
+	return nil
 }
 
 // Client contains options for interaction with the iam.
@@ -318,6 +926,27 @@ type client struct {
 	endpoint string
 	certPool *x509.CertPool
 
+	// endpoints are set if several endpoints are provided, endpoint is the first of them.
+	endpoints   []string
+	balancing   Balancing
+	maxFailures int
+	cooldown    time.Duration
+	trace       trace.Trace
+
+	discoveryEndpoint string
+	discoveryExpires  time.Time // moment of the next discovery, zero until the first one succeeds
+
+	serverName    string
+	minTLSVersion uint16
+	pins          [][sha256.Size]byte
+	backupPins    [][sha256.Size]byte
+
+	clientCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+
+	proxy       *url.URL
+	dialer      contextDialer
+	dialOptions []grpc.DialOption
+
 	// If insecureSkipVerify is true, client accepts any TLS certificate
 	// presented by the iam server and any host name in that certificate.
 	//
@@ -333,20 +962,38 @@ type client struct {
 	tokenTTL time.Duration
 	audience string
 
+	retryAttempts int
+	retryBackoff  time.Duration
+	refreshRatio  float64
+
+	impersonate string
+	oauthToken  string
+
 	once    sync.Once
 	mu      sync.RWMutex
 	err     error
 	token   string
 	expires time.Time
 
-	// transport is a stub used for tests.
-	transport transport
+	transport TokenExchanger
+	// exchanger is provided with WithTransport and replaces grpc transport.
+	exchanger TokenExchanger
 
 	sourceInfo string
 
 	fallback credentials.Credentials
 
-	clock clockwork.Clock
+	clock Clock
+
+	// origins maps a configured field to the option which set it.
+	origins map[string]string
+	// certFileOrigin and insecureOrigin are set when the cert pool was extended from a file and when
+	// verification was explicitly disabled.
+	certFileOrigin string
+	insecureOrigin string
+	// certFiles keeps certificates of WithCertPoolFile to carry them over to the pool of WithSystemCertPool
+	// and WithInstallation.
+	certFiles [][]byte
 }
 
 func (c *client) String() string {
@@ -379,11 +1026,11 @@ func (c *client) Token(ctx context.Context) (string, error) {
 	if !c.expired() {
 		return c.token, nil
 	}
-	jwtToken, err := c.jwt(now)
-	if err != nil {
-		return c.token, err
+	if err := c.discover(ctx); err != nil {
+		return "", err
 	}
-	token, expires, err := c.transport.CreateToken(ctx, jwtToken)
+	token, expires, err := c.createToken(ctx, now)
+	trace.TraceOnTokenRefreshed(c.trace, expires, err)
 	if err != nil {
 		return "", &createTokenError{
 			cause:  err,
@@ -391,11 +1038,78 @@ func (c *client) Token(ctx context.Context) (string, error) {
 		}
 	}
 	c.token = token
-	c.expires = now.Add(expires.Sub(now) / 2)
+	c.expires = c.refreshAt(now, expires)
 
 	return token, nil
 }
 
+// createToken exchanges the jwt or, if WithOAuthToken is provided, the OAuth token for an iam token.
+func (c *client) createToken(ctx context.Context, now time.Time) (string, time.Time, error) {
+	if c.oauthToken != "" {
+		t, ok := c.transport.(oauthExchanger)
+		if !ok {
+			return "", time.Time{}, fmt.Errorf("iam: transport does not support OAuth tokens")
+		}
+
+		return c.retry(ctx, func() (string, time.Time, error) {
+			return t.CreateTokenFromOAuth(ctx, c.oauthToken)
+		})
+	}
+	jwtToken, err := c.jwt(now)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return c.retry(ctx, func() (string, time.Time, error) {
+		return c.transport.CreateToken(ctx, jwtToken)
+	})
+}
+
+// refreshAt returns the moment when the token issued at now is refreshed (see WithRefreshRatio).
+func (c *client) refreshAt(now, expires time.Time) time.Time {
+	if c.refreshRatio > 0 {
+		return now.Add(time.Duration(float64(expires.Sub(now)) * c.refreshRatio))
+	}
+
+	return now.Add(expires.Sub(now) / 2)
+}
+
+// retry calls createToken with retries of endpoint failures (see WithRetry).
+func (c *client) retry(
+	ctx context.Context, createToken func() (string, time.Time, error),
+) (string, time.Time, error) {
+	for attempt := 1; ; attempt++ {
+		token, expires, err := createToken()
+		if err == nil || attempt >= c.retryAttempts || !isEndpointFailure(err) {
+			return token, expires, err
+		}
+		if !c.sleep(ctx, c.retryBackoff*time.Duration(attempt)) {
+			return "", time.Time{}, err
+		}
+	}
+}
+
+// sleep waits for d with the timer of the client clock if it provides one, it returns false if ctx is
+// done before.
+func (c *client) sleep(ctx context.Context, d time.Duration) bool {
+	if clock, ok := c.clock.(timerClock); ok {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-clock.After(d):
+			return true
+		}
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
 func (c *client) init() error {
 	c.once.Do(func() {
 		if c.endpoint == "" {
@@ -410,11 +1124,7 @@ func (c *client) init() error {
 			c.tokenTTL = DefaultTokenTTL
 		}
 		if c.transport == nil {
-			c.transport = &grpcTransport{
-				endpoint:           c.endpoint,
-				certPool:           c.certPool,
-				insecureSkipVerify: c.insecureSkipVerify,
-			}
+			c.transport = c.newTransport()
 		}
 	})
 
@@ -422,7 +1132,7 @@ func (c *client) init() error {
 }
 
 func (c *client) expired() bool {
-	return c.clock.Since(c.expires) > 0
+	return c.clock.Now().After(c.expires)
 }
 
 // By default, Go RSA PSS uses PSSSaltLengthAuto, but RFC states that salt size
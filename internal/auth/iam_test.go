@@ -1,9 +1,15 @@
 package auth
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"log"
+	"os"
 	"testing"
 	"time"
 
@@ -11,13 +17,11 @@ import (
 	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-)
-
-type TransportFunc func(context.Context, string) (string, time.Time, error)
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
-func (f TransportFunc) CreateToken(ctx context.Context, jwt string) (string, time.Time, error) {
-	return f(ctx, jwt)
-}
+	"github.com/ydb-platform/ydb-go-yc/trace"
+)
 
 func TestClientToken(t *testing.T) {
 	const (
@@ -62,7 +66,7 @@ func TestClientToken(t *testing.T) {
 		tokenTTL: ttl,
 
 		// Stub the real transport logic to check jwt token for correctness.
-		transport: TransportFunc(func(ctx context.Context, jwtString string) (
+		transport: TokenExchangerFunc(func(ctx context.Context, jwtString string) (
 			string, time.Time, error,
 		) {
 			var claims jwt.RegisteredClaims
@@ -149,5 +153,230 @@ func TestOptionsConfig(t *testing.T) {
 		assert.Equal(t, audience, cl.audience)
 		assert.Equal(t, endpoint, cl.endpoint)
 		assert.Equal(t, ttl, cl.tokenTTL)
+		assert.False(t, cl.insecureSkipVerify, "TLS verification must be enabled by default")
+	}
+}
+
+func TestOptionsConflicts(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	serviceKey := func(endpoint string) string {
+		der := x509.MarshalPKCS1PrivateKey(key)
+		data, err := json.Marshal(map[string]string{
+			"id":                 "key-id",
+			"service_account_id": "sa-id",
+			"private_key":        string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der})),
+			"endpoint":           endpoint,
+		})
+		require.NoError(t, err)
+
+		return string(data)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		opts     []ClientOption
+		conflict []string
+	}{
+		{
+			name:     "ServiceKeyAndPrivateKey",
+			opts:     []ClientOption{WithServiceKey(serviceKey("")), WithPrivateKey(other)},
+			conflict: []string{"WithServiceKey", "WithPrivateKey"},
+		},
+		{
+			name:     "ServiceKeyAndKeyID",
+			opts:     []ClientOption{WithKeyID("another"), WithServiceKey(serviceKey(""))},
+			conflict: []string{"WithKeyID", "WithServiceKey"},
+		},
+		{
+			name:     "ServiceKeyEndpointAndEndpoint",
+			opts:     []ClientOption{WithServiceKey(serviceKey("a:443")), WithEndpoint("b:443")},
+			conflict: []string{"WithServiceKey", "WithEndpoint"},
+		},
+		{
+			name:     "InsecureSkipVerifyAndCertPool",
+			opts:     []ClientOption{WithInsecureSkipVerify(true), WithCertPool(x509.NewCertPool())},
+			conflict: []string{"WithInsecureSkipVerify(true)", "WithCertPool"},
+		},
+		{
+			name: "SameValues",
+			opts: []ClientOption{
+				WithServiceKey(serviceKey("a:443")),
+				WithEndpoint("a:443"),
+				WithPrivateKey(key),
+				WithInsecureSkipVerify(false),
+				WithCertPool(x509.NewCertPool()),
+			},
+		},
+		{
+			name: "RepeatedOption",
+			opts: []ClientOption{WithEndpoint("a:443"), WithEndpoint("b:443")},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient(tt.opts...)
+			if len(tt.conflict) == 0 {
+				require.NoError(t, err)
+
+				return
+			}
+			require.ErrorIs(t, err, ErrConflictingOptions)
+			for _, option := range tt.conflict {
+				assert.Contains(t, err.Error(), option)
+			}
+		})
+	}
+}
+
+func TestClientRetryAndRefreshRatio(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var (
+		fakeTime = clockwork.NewFakeClock()
+		calls    int
+		failures = 2
+	)
+	c := client{
+		clock:    fakeTime,
+		endpoint: "endpoint",
+		key:      key,
+		tokenTTL: time.Hour,
+		transport: TokenExchangerFunc(func(ctx context.Context, jwt string) (string, time.Time, error) {
+			calls++
+			if calls <= failures {
+				return "", time.Time{}, status.Error(codes.Unavailable, "unavailable")
+			}
+
+			return "token", fakeTime.Now().Add(time.Hour), nil
+		}),
+	}
+	require.NoError(t, WithRetry(3, time.Minute)(&c))
+	require.NoError(t, WithRefreshRatio(0.9)(&c))
+
+	// Backoff is waited with the fake clock, not in real time.
+	go func() {
+		for attempt := 1; attempt <= failures; attempt++ {
+			fakeTime.BlockUntil(1)
+			fakeTime.Advance(time.Duration(attempt) * time.Minute)
+		}
+	}()
+	token, err := c.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token", token)
+	require.Equal(t, 3, calls)
+
+	// Token is cached for 0.9 of its lifetime.
+	fakeTime.Advance(50 * time.Minute)
+	_, err = c.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	fakeTime.Advance(5 * time.Minute)
+	_, err = c.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 4, calls)
+
+	require.Error(t, WithRetry(0, time.Second)(&c))
+	require.Error(t, WithRefreshRatio(1)(&c))
+}
+
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.now
+}
+
+func TestWithClock(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var (
+		clock    = &manualClock{now: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+		issuedAt []time.Time
+	)
+	c, err := NewClient(WithPrivateKey(key), WithKeyID("key"), WithIssuer("issuer"), WithClock(clock),
+		WithTransport(TokenExchangerFunc(func(ctx context.Context, jwtString string) (string, time.Time, error) {
+			var claims jwt.RegisteredClaims
+			_, _, err := jwt.NewParser().ParseUnverified(jwtString, &claims)
+			require.NoError(t, err)
+			issuedAt = append(issuedAt, claims.IssuedAt.UTC())
+
+			return "token", clock.Now().Add(time.Hour), nil
+		})),
+	)
+	require.NoError(t, err)
+
+	_, err = c.Token(context.Background())
+	require.NoError(t, err)
+	clock.now = clock.now.Add(29 * time.Minute)
+	_, err = c.Token(context.Background())
+	require.NoError(t, err)
+	require.Len(t, issuedAt, 1)
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	_, err = c.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{
+		time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 10, 31, 0, 0, time.UTC),
+	}, issuedAt)
+
+	_, err = NewClient(WithClock(nil))
+	require.Error(t, err)
+}
+
+func TestWithTransport(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	exchanger := TokenExchangerFunc(func(ctx context.Context, jwt string) (string, time.Time, error) {
+		return "gateway-token", time.Now().Add(time.Hour), nil
+	})
+	c, err := NewClient(WithPrivateKey(key), WithTransport(exchanger))
+	require.NoError(t, err)
+	token, err := c.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "gateway-token", token)
+
+	_, err = NewClient(WithPrivateKey(key), WithTransport(exchanger), WithEndpointDiscovery(DefaultDiscoveryEndpoint))
+	require.ErrorIs(t, err, ErrConflictingOptions)
+	_, err = NewClient(WithTransport(nil))
+	require.Error(t, err)
+}
+
+func TestInsecureSkipVerifyTrace(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var endpoints []string
+	tr := trace.Trace{
+		OnInsecureSkipVerify: func(info trace.InsecureSkipVerifyInfo) {
+			endpoints = append(endpoints, info.Endpoint)
+		},
 	}
+	_, err = NewClient(WithPrivateKey(key), WithEndpoint("iam:443"), WithInsecureSkipVerify(true), WithTrace(tr))
+	require.NoError(t, err)
+	require.Equal(t, []string{"iam:443"}, endpoints)
+
+	_, err = NewClient(WithPrivateKey(key), WithEndpoint("iam:443"), WithTrace(tr))
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+
+	// Without the trace the warning is logged.
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	_, err = NewClient(WithPrivateKey(key), WithEndpoint("iam:443"), WithInsecureSkipVerify(true))
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), `TLS certificate verification of iam endpoint "iam:443" is disabled`)
+	buf.Reset()
+	_, err = NewClient(WithPrivateKey(key), WithEndpoint("iam:443"), WithInsecureSkipVerify(true), WithTrace(tr))
+	require.NoError(t, err)
+	require.Empty(t, buf.String())
 }
@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+
+	"github.com/ydb-platform/ydb-go-yc/trace"
+)
+
+// DefaultExpiryWarning is the time before expiration of a static iam token when
+// trace.Trace.OnTokenExpiring is called.
+const DefaultExpiryWarning = 10 * time.Minute
+
+var (
+	ErrTokenExpired = errors.New("iam token expired")
+	ErrTokenEmpty   = errors.New("iam token is empty")
+
+	_ credentials.Credentials = (*IAMTokenCredentials)(nil)
+)
+
+// IAMTokenCredentials provides a pre-issued iam token read from a file or an environment variable.
+// The source is read again when it changes.
+//
+// The source contains either the token itself or JSON as printed by `yc iam create-token --format json`:
+//
+//	{"iam_token": "t1.9euelZ...", "expires_at": "2024-01-01T12:00:00Z"}
+type IAMTokenCredentials struct {
+	source string
+	read   func() ([]byte, error)
+	// stat returns the version of the source, the source is read only when it changes. Nil if the
+	// source has no cheap version and is read on each Token call.
+	stat func() (sourceVersion, error)
+
+	expiresAt     time.Time // provided with WithIAMTokenExpiresAt for the first token without expiration
+	expiryWarning time.Duration
+	trace         trace.Trace
+	clock         Clock
+
+	mu      sync.Mutex
+	version sourceVersion
+	content []byte
+	token   string
+	expires time.Time
+	first   string // the first token read, expiresAt applies only to it
+	warned  string // token for which OnTokenExpiring was called
+}
+
+// sourceVersion identifies the content of the file source.
+type sourceVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+type IAMTokenOption func(*IAMTokenCredentials)
+
+// WithIAMTokenExpiresAt set expiration of the token when the source does not contain it. It applies
+// only to the token read first, rotated tokens have their own expiration or none.
+func WithIAMTokenExpiresAt(expiresAt time.Time) IAMTokenOption {
+	return func(c *IAMTokenCredentials) {
+		c.expiresAt = expiresAt
+	}
+}
+
+// WithIAMTokenExpiryWarning set the time before expiration when trace.Trace.OnTokenExpiring is called.
+func WithIAMTokenExpiryWarning(d time.Duration) IAMTokenOption {
+	return func(c *IAMTokenCredentials) {
+		c.expiryWarning = d
+	}
+}
+
+// WithIAMTokenTrace set trace of the credentials events. Several traces are composed.
+func WithIAMTokenTrace(t trace.Trace) IAMTokenOption {
+	return func(c *IAMTokenCredentials) {
+		c.trace = c.trace.Compose(t)
+	}
+}
+
+// WithIAMTokenClock set the clock used to check expiration of the token, nil keeps the real clock.
+func WithIAMTokenClock(clock Clock) IAMTokenOption {
+	return func(c *IAMTokenCredentials) {
+		if clock != nil {
+			c.clock = clock
+		}
+	}
+}
+
+func newIAMTokenCredentials(
+	source string, read func() ([]byte, error), stat func() (sourceVersion, error), opts ...IAMTokenOption,
+) *IAMTokenCredentials {
+	c := &IAMTokenCredentials{
+		source:        source,
+		read:          read,
+		stat:          stat,
+		expiryWarning: DefaultExpiryWarning,
+		clock:         clockwork.NewRealClock(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// NewIAMTokenFileCredentials makes credentials which read iam token from the file. The file is read
+// again when its modification time or size changes.
+func NewIAMTokenFileCredentials(path string, opts ...IAMTokenOption) *IAMTokenCredentials {
+	return newIAMTokenCredentials("file "+path, func() ([]byte, error) {
+		return os.ReadFile(path)
+	}, func() (sourceVersion, error) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return sourceVersion{}, err
+		}
+
+		return sourceVersion{modTime: info.ModTime(), size: info.Size()}, nil
+	}, opts...)
+}
+
+// NewIAMTokenEnvCredentials makes credentials which read iam token from the environment variable
+// on each Token call.
+func NewIAMTokenEnvCredentials(name string, opts ...IAMTokenOption) *IAMTokenCredentials {
+	return newIAMTokenCredentials("environment variable "+name, func() ([]byte, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", name)
+		}
+
+		return []byte(value), nil
+	}, nil, opts...)
+}
+
+// Token returns the token from the source. It fails with ErrTokenExpired if the expiration of the token
+// is known and has passed.
+func (c *IAMTokenCredentials) Token(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.reload(); err != nil {
+		return "", fmt.Errorf("iam: read token from %s: %w", c.source, err)
+	}
+	if c.expires.IsZero() {
+		return c.token, nil
+	}
+	now := c.clock.Now()
+	if !now.Before(c.expires) {
+		return "", fmt.Errorf("iam: token from %s: %w at %s", c.source, ErrTokenExpired, c.expires.Format(time.RFC3339))
+	}
+	if c.expires.Sub(now) <= c.expiryWarning && c.warned != c.token {
+		c.warned = c.token
+		trace.TraceOnTokenExpiring(c.trace, c.source, c.expires)
+	}
+
+	return c.token, nil
+}
+
+// reload reads the source if its version changed and parses the token if the content changed.
+func (c *IAMTokenCredentials) reload() error {
+	var version sourceVersion
+	if c.stat != nil {
+		var err error
+		if version, err = c.stat(); err != nil {
+			return err
+		}
+		if c.token != "" && version.modTime.Equal(c.version.modTime) && version.size == c.version.size {
+			return nil
+		}
+	}
+	content, err := c.read()
+	if err != nil {
+		return err
+	}
+	if c.token != "" && bytes.Equal(content, c.content) {
+		c.version = version
+
+		return nil
+	}
+	token, expires, err := parseIAMToken(content)
+	if err != nil {
+		return err
+	}
+	if c.first == "" {
+		c.first = token
+	}
+	if expires.IsZero() && token == c.first {
+		expires = c.expiresAt
+	}
+	c.version, c.content = version, content
+	c.token, c.expires = token, expires
+
+	return nil
+}
+
+// parseIAMToken parses the token and its expiration from JSON, or takes the content as the token.
+func parseIAMToken(content []byte) (token string, expires time.Time, _ error) {
+	content = bytes.TrimSpace(content)
+	if bytes.HasPrefix(content, []byte("{")) {
+		var res struct {
+			IAMToken  string    `json:"iam_token"`  //nolint:tagliatelle // yc CLI output format.
+			ExpiresAt time.Time `json:"expires_at"` //nolint:tagliatelle // yc CLI output format.
+		}
+		if err := json.Unmarshal(content, &res); err != nil {
+			return "", time.Time{}, err
+		}
+		token, expires = res.IAMToken, res.ExpiresAt
+	} else {
+		token = string(content)
+	}
+	if token == "" || strings.ContainsAny(token, " \t\r\n") {
+		return "", time.Time{}, ErrTokenEmpty
+	}
+
+	return token, expires, nil
+}
+
+func (c *IAMTokenCredentials) String() string {
+	return "iam.TokenCredentials from " + c.source
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ydb-platform/ydb-go-yc/trace"
+)
+
+func TestIAMTokenFileCredentials(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "token.json")
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+
+	var expiring []trace.TokenExpiringInfo
+	creds := NewIAMTokenFileCredentials(path, WithIAMTokenTrace(trace.Trace{
+		OnTokenExpiring: func(info trace.TokenExpiringInfo) {
+			expiring = append(expiring, info)
+		},
+	}))
+	creds.clock = clock
+
+	_, err := creds.Token(ctx)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"iam_token":"t1","expires_at":"2024-01-01T11:00:00Z"}`), 0o600))
+	token, err := creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "t1", token)
+	require.Empty(t, expiring)
+
+	clock.Advance(55 * time.Minute)
+	_, err = creds.Token(ctx)
+	require.NoError(t, err)
+	_, err = creds.Token(ctx)
+	require.NoError(t, err)
+	require.Len(t, expiring, 1)
+	require.Equal(t, time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC), expiring[0].ExpiresAt)
+
+	clock.Advance(5 * time.Minute)
+	_, err = creds.Token(ctx)
+	require.ErrorIs(t, err, ErrTokenExpired)
+
+	// the file is read again after change.
+	require.NoError(t, os.WriteFile(path, []byte("t2\n"), 0o600))
+	require.NoError(t, os.Chtimes(path, clock.Now(), clock.Now()))
+	token, err = creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "t2", token)
+}
+
+func TestIAMTokenEnvCredentials(t *testing.T) {
+	ctx := context.Background()
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	creds := NewIAMTokenEnvCredentials("YC_TEST_IAM_TOKEN",
+		WithIAMTokenExpiresAt(clock.Now().Add(time.Hour)),
+	)
+	creds.clock = clock
+
+	t.Setenv("YC_TEST_IAM_TOKEN", "t1")
+	token, err := creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "t1", token)
+
+	clock.Advance(time.Hour)
+	_, err = creds.Token(ctx)
+	require.True(t, errors.Is(err, ErrTokenExpired), err)
+
+	// The expiration of WithIAMTokenExpiresAt does not apply to rotated tokens.
+	t.Setenv("YC_TEST_IAM_TOKEN", "t2")
+	token, err = creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "t2", token)
+
+	t.Setenv("YC_TEST_IAM_TOKEN", `{"iam_token":"t3","expires_at":"2099-01-01T00:00:00Z"}`)
+	token, err = creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "t3", token)
+
+	t.Setenv("YC_TEST_IAM_TOKEN", "")
+	_, err = creds.Token(ctx)
+	require.ErrorIs(t, err, ErrTokenEmpty)
+}
+
+func TestIAMTokenFileRead(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("t1"), 0o600))
+	modTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+	creds := NewIAMTokenFileCredentials(path)
+	reads := 0
+	read := creds.read
+	creds.read = func() ([]byte, error) {
+		reads++
+
+		return read()
+	}
+	for i := 0; i < 3; i++ {
+		token, err := creds.Token(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "t1", token)
+	}
+	require.Equal(t, 1, reads)
+
+	// A rotated token of another size is read even if the modification time is kept.
+	require.NoError(t, os.WriteFile(path, []byte("t22"), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+	token, err := creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "t22", token)
+	require.Equal(t, 2, reads)
+}
+
+func TestIAMTokenAsFallback(t *testing.T) {
+	t.Setenv("YC_TEST_IAM_TOKEN", "fallback")
+	c, err := NewClient(
+		WithServiceFile(filepath.Join(t.TempDir(), "missing.json")),
+		WithFallbackCredentials(NewIAMTokenEnvCredentials("YC_TEST_IAM_TOKEN")),
+	)
+	require.NoError(t, err)
+	token, err := c.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "fallback", token)
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+)
+
+var errImpersonationNotSupported = errors.New("iam: transport does not support impersonation")
+
+// Impersonator issues iam tokens of service accounts using IamTokenService.CreateForServiceAccount
+// authorized with the token of base credentials. Tokens are cached per service account.
+type Impersonator struct {
+	base credentials.Credentials
+	c    *client // connection parameters of the iam endpoint
+
+	mu       sync.Mutex
+	accounts map[string]*impersonatedCredentials
+}
+
+// NewImpersonator creates Impersonator on top of base credentials, e.g. made by NewClient or metadata
+// credentials. Connection parameters of the iam endpoint are taken from opts, key options are ignored.
+// If WithTransport is provided, its exchanger must implement ImpersonationExchanger.
+func NewImpersonator(base credentials.Credentials, opts ...ClientOption) (*Impersonator, error) {
+	c := &client{
+		endpoint: DefaultEndpoint,
+		certPool: defaultCertPool(),
+		clock:    clockwork.NewRealClock(),
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("cannot create impersonator: %w", err)
+		}
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("cannot create impersonator: %w", err)
+	}
+	if c.exchanger != nil {
+		if _, ok := c.exchanger.(ImpersonationExchanger); !ok {
+			return nil, fmt.Errorf("cannot create impersonator: %w", errImpersonationNotSupported)
+		}
+		c.transport = c.exchanger
+	}
+
+	return newImpersonator(base, c), nil
+}
+
+// NewImpersonatedCredentials returns credentials of the service account serviceAccountID issued to
+// the identity of base credentials.
+func NewImpersonatedCredentials(
+	base credentials.Credentials, serviceAccountID string, opts ...ClientOption,
+) (credentials.Credentials, error) {
+	i, err := NewImpersonator(base, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.ServiceAccount(serviceAccountID), nil
+}
+
+func newImpersonator(base credentials.Credentials, c *client) *Impersonator {
+	return &Impersonator{
+		base:     base,
+		c:        c,
+		accounts: make(map[string]*impersonatedCredentials),
+	}
+}
+
+// ServiceAccount returns credentials of the service account. Credentials of the same service account
+// share the cached token.
+func (i *Impersonator) ServiceAccount(serviceAccountID string) credentials.Credentials {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if c, ok := i.accounts[serviceAccountID]; ok {
+		return c
+	}
+	c := &impersonatedCredentials{
+		impersonator:     i,
+		serviceAccountID: serviceAccountID,
+	}
+	i.accounts[serviceAccountID] = c
+
+	return c
+}
+
+func (i *Impersonator) createToken(ctx context.Context, serviceAccountID string) (string, time.Time, error) {
+	iamToken, err := i.base.Token(ctx)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	t, err := i.transport(ctx)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return i.c.retry(ctx, func() (string, time.Time, error) {
+		return t.CreateTokenForServiceAccount(ctx, iamToken, serviceAccountID)
+	})
+}
+
+// transport returns transport of the iam endpoint, the endpoint is discovered if needed.
+func (i *Impersonator) transport(ctx context.Context) (ImpersonationExchanger, error) {
+	if err := i.c.init(); err != nil {
+		return nil, err
+	}
+	i.c.mu.Lock()
+	defer i.c.mu.Unlock()
+	if err := i.c.discover(ctx); err != nil {
+		return nil, err
+	}
+	t, ok := i.c.transport.(ImpersonationExchanger)
+	if !ok {
+		return nil, errImpersonationNotSupported
+	}
+
+	return t, nil
+}
+
+type impersonatedCredentials struct {
+	impersonator     *Impersonator
+	serviceAccountID string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time // moment of refresh
+}
+
+func (c *impersonatedCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.impersonator.c.clock.Now()
+	if c.token != "" && now.Before(c.expires) {
+		return c.token, nil
+	}
+	token, expires, err := c.impersonator.createToken(ctx, c.serviceAccountID)
+	if err != nil {
+		return "", &createTokenError{
+			cause:  err,
+			reason: fmt.Sprintf("impersonate service account '%s': %v", c.serviceAccountID, err),
+		}
+	}
+	c.token = token
+	c.expires = c.impersonator.c.refreshAt(now, expires)
+
+	return token, nil
+}
+
+func (c *impersonatedCredentials) String() string {
+	if s, ok := c.impersonator.base.(fmt.Stringer); ok {
+		return "iam.ImpersonatedCredentials(" + c.serviceAccountID + ") on top of " + s.String()
+	}
+
+	return "iam.ImpersonatedCredentials(" + c.serviceAccountID + ")"
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+	v1 "github.com/yandex-cloud/go-genproto/yandex/cloud/iam/v1"
+	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestImpersonator(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	calls := map[string]int{}
+	s := StubTokenService{
+		OnCreateForServiceAccount: func(ctx context.Context, req *v1.CreateIamTokenForServiceAccountRequest) (
+			*v1.CreateIamTokenResponse, error,
+		) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			if auth := md.Get("authorization"); len(auth) != 1 || auth[0] != "Bearer base" {
+				return nil, status.Error(codes.Unauthenticated, "unexpected authorization")
+			}
+			calls[req.GetServiceAccountId()]++
+
+			return &v1.CreateIamTokenResponse{
+				IamToken:  "token-" + req.GetServiceAccountId(),
+				ExpiresAt: timestamppb.New(clock.Now().Add(time.Hour)),
+			}, nil
+		},
+	}
+	addr, stop, err := s.ListenAndServe()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, stop())
+	}()
+
+	i := newImpersonator(credentials.NewAccessTokenCredentials("base"), &client{
+		endpoint:  addr.String(),
+		clock:     clock,
+		transport: &grpcTransport{endpoint: addr.String(), insecure: true},
+	})
+	ctx := context.Background()
+
+	for _, id := range []string{"sa-1", "sa-2", "sa-1"} {
+		token, err := i.ServiceAccount(id).Token(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "token-"+id, token)
+	}
+	require.Equal(t, map[string]int{"sa-1": 1, "sa-2": 1}, calls)
+
+	clock.Advance(31 * time.Minute)
+	_, err = i.ServiceAccount("sa-1").Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, calls["sa-1"])
+
+	_, err = newImpersonator(credentials.NewAccessTokenCredentials("other"), i.c).ServiceAccount("sa-1").Token(ctx)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestWithImpersonatedServiceAccount(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	c, err := NewClient(
+		WithPrivateKey(key), WithKeyID("key"), WithIssuer("issuer"),
+		WithImpersonatedServiceAccount("sa-1"),
+	)
+	require.NoError(t, err)
+	require.IsType(t, &impersonatedCredentials{}, c)
+
+	_, err = NewClient(WithPrivateKey(key), WithImpersonatedServiceAccount(""))
+	require.Error(t, err)
+}
+
+// stubImpersonationExchanger issues tokens "<service account>@<iam token>".
+type stubImpersonationExchanger struct {
+	TokenExchangerFunc
+}
+
+func (stubImpersonationExchanger) CreateTokenForServiceAccount(
+	ctx context.Context, iamToken, serviceAccountID string,
+) (string, time.Time, error) {
+	return serviceAccountID + "@" + iamToken, time.Now().Add(time.Hour), nil
+}
+
+func TestImpersonationWithTransport(t *testing.T) {
+	exchanger := stubImpersonationExchanger{
+		TokenExchangerFunc: func(ctx context.Context, jwt string) (string, time.Time, error) {
+			return "primary", time.Now().Add(time.Hour), nil
+		},
+	}
+	ctx := context.Background()
+
+	creds, err := NewImpersonatedCredentials(credentials.NewAccessTokenCredentials("base"), "sa-1",
+		WithTransport(exchanger),
+	)
+	require.NoError(t, err)
+	token, err := creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "sa-1@base", token)
+
+	_, err = NewImpersonator(credentials.NewAccessTokenCredentials("base"), WithTransport(exchanger.TokenExchangerFunc))
+	require.ErrorIs(t, err, errImpersonationNotSupported)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	creds, err = NewClient(WithPrivateKey(key), WithTransport(exchanger), WithImpersonatedServiceAccount("sa-1"))
+	require.NoError(t, err)
+	token, err = creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "sa-1@primary", token)
+
+	_, err = NewClient(WithPrivateKey(key), WithTransport(exchanger.TokenExchangerFunc),
+		WithImpersonatedServiceAccount("sa-1"),
+	)
+	require.Error(t, err)
+
+	// The service account is impersonated by fallback credentials if the primary ones fail.
+	creds, err = NewClient(
+		WithServiceFile("/not/exists/sa.json"),
+		WithFallbackCredentials(credentials.NewAccessTokenCredentials("fallback")),
+		WithTransport(exchanger),
+		WithImpersonatedServiceAccount("sa-1"),
+	)
+	require.NoError(t, err)
+	token, err = creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "sa-1@fallback", token)
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ServiceAccountKeyInfo describes the service account key file without its private part.
+type ServiceAccountKeyInfo struct {
+	KeyID            string
+	ServiceAccountID string
+	// Endpoint is the iam endpoint of the key file, empty if the default endpoint is used.
+	Endpoint string
+	// KeyAlgorithm is the algorithm declared in the file, e.g. RSA_2048.
+	KeyAlgorithm string
+	CreatedAt    string
+
+	// PEMType is the type of the PEM block of the private key, e.g. PRIVATE KEY.
+	PEMType string
+	// Encoding is PKCS#1 or PKCS#8.
+	Encoding string
+	// KeyType is the type of the private key: RSA, ECDSA or Ed25519. Only RSA keys are supported.
+	KeyType string
+	// KeySize is the size of RSA modulus in bits.
+	KeySize int
+
+	// HasPublicKey reports whether the file contains the public key, PublicKeyMatches whether
+	// it is the public part of the private key.
+	HasPublicKey     bool
+	PublicKeyMatches bool
+}
+
+// InspectServiceAccountKey parses the service account key data as WithServiceKey does and describes
+// it. On error, the returned info contains the fields recognized before the failure, and the error
+// wraps ErrServiceFileInvalid or ErrKeyCannotBeParsed with the reason.
+func InspectServiceAccountKey(data []byte) (*ServiceAccountKeyInfo, error) {
+	file, key, err := parseServiceAccountKey(data)
+	info := &ServiceAccountKeyInfo{
+		KeyID:            file.ID,
+		ServiceAccountID: file.ServiceAccountID,
+		Endpoint:         file.Endpoint,
+		KeyAlgorithm:     file.KeyAlgorithm,
+		CreatedAt:        file.CreatedAt,
+		HasPublicKey:     file.PublicKey != "",
+	}
+	describePrivateKey(info, file.PrivateKey)
+	if err != nil {
+		return info, inspectError(err, &file, info)
+	}
+	info.KeySize = key.N.BitLen()
+	if info.HasPublicKey {
+		info.PublicKeyMatches = publicKeyMatches(file.PublicKey, key)
+	}
+
+	return info, nil
+}
+
+// describePrivateKey fills the format of the private key, it does not fail on invalid keys.
+func describePrivateKey(info *ServiceAccountKeyInfo, privateKey string) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return
+	}
+	info.PEMType = block.Type
+	if _, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		info.Encoding, info.KeyType = "PKCS#1", "RSA"
+
+		return
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return
+	}
+	info.Encoding = "PKCS#8"
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		info.KeyType = "RSA"
+	case *ecdsa.PrivateKey:
+		info.KeyType = "ECDSA"
+	case ed25519.PrivateKey:
+		info.KeyType = "Ed25519"
+	}
+}
+
+// inspectError adds the reason to errors of parseServiceAccountKey.
+func inspectError(err error, file *serviceAccountKey, info *ServiceAccountKeyInfo) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &syntaxErr) || errors.As(err, &typeErr):
+		return fmt.Errorf("%w: file is not valid JSON: %v", ErrServiceFileInvalid, err)
+	case errors.Is(err, ErrServiceFileInvalid):
+		var missing []string
+		for _, field := range []struct{ name, value string }{
+			{"id", file.ID},
+			{"service_account_id", file.ServiceAccountID},
+			{"private_key", file.PrivateKey},
+		} {
+			if field.value == "" {
+				missing = append(missing, field.name)
+			}
+		}
+
+		return fmt.Errorf("%w: missing fields %s", err, strings.Join(missing, ", "))
+	case errors.Is(err, ErrKeyCannotBeParsed) && info.PEMType == "":
+		return fmt.Errorf("%w: private_key does not contain a PEM block", err)
+	case errors.Is(err, ErrKeyCannotBeParsed) && info.KeyType != "":
+		return fmt.Errorf("%w: %s keys are not supported, RSA key is required", err, info.KeyType)
+	case info.PEMType != "" && info.Encoding == "":
+		return fmt.Errorf("%w: PEM block '%s' is neither PKCS#1 nor PKCS#8: %v", ErrKeyCannotBeParsed, info.PEMType, err)
+	default:
+		return fmt.Errorf("invalid service account key file: %w", err)
+	}
+}
+
+func publicKeyMatches(publicKey string, key *rsa.PrivateKey) bool {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return false
+	}
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return false
+	}
+
+	return key.PublicKey.Equal(public)
+}
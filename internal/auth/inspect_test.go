@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectServiceAccountKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pkcs8 := func(key interface{}) string {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+
+		return "PLEASE DO NOT REMOVE THIS LINE! Yandex.Cloud SA Key ID <key>\n" +
+			string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	}
+	public := func(key *rsa.PrivateKey) string {
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		require.NoError(t, err)
+
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	}
+	keyData := func(fields map[string]string) []byte {
+		data, err := json.Marshal(fields)
+		require.NoError(t, err)
+
+		return data
+	}
+
+	info, err := InspectServiceAccountKey(keyData(map[string]string{
+		"id": "key", "service_account_id": "sa", "key_algorithm": "RSA_2048",
+		"private_key": pkcs8(key), "public_key": public(key),
+	}))
+	require.NoError(t, err)
+	require.Equal(t, &ServiceAccountKeyInfo{
+		KeyID:            "key",
+		ServiceAccountID: "sa",
+		KeyAlgorithm:     "RSA_2048",
+		PEMType:          "PRIVATE KEY",
+		Encoding:         "PKCS#8",
+		KeyType:          "RSA",
+		KeySize:          2048,
+		HasPublicKey:     true,
+		PublicKeyMatches: true,
+	}, info)
+
+	info, err = InspectServiceAccountKey(keyData(map[string]string{
+		"id": "key", "service_account_id": "sa", "private_key": pkcs8(key), "public_key": public(other),
+	}))
+	require.NoError(t, err)
+	require.False(t, info.PublicKeyMatches)
+
+	_, err = InspectServiceAccountKey(keyData(map[string]string{"id": "key", "private_key": pkcs8(key)}))
+	require.ErrorIs(t, err, ErrServiceFileInvalid)
+	require.Contains(t, err.Error(), "missing fields service_account_id")
+
+	info, err = InspectServiceAccountKey(keyData(map[string]string{
+		"id": "key", "service_account_id": "sa", "private_key": pkcs8(ecKey),
+	}))
+	require.ErrorIs(t, err, ErrKeyCannotBeParsed)
+	require.Contains(t, err.Error(), "ECDSA keys are not supported")
+	require.Equal(t, "sa", info.ServiceAccountID)
+
+	_, err = InspectServiceAccountKey(keyData(map[string]string{
+		"id": "key", "service_account_id": "sa", "private_key": "MIIEvQIBADANBgkqhkiG9w0BAQEFAASC",
+	}))
+	require.ErrorIs(t, err, ErrKeyCannotBeParsed)
+	require.Contains(t, err.Error(), "does not contain a PEM block")
+
+	for _, tt := range []struct {
+		name string
+		data string
+	}{
+		{name: "Empty", data: ""},
+		{name: "Truncated", data: `{"id": "key", "service_account_id": "sa"`},
+		{name: "NotObject", data: `["key"]`},
+		{name: "WrongFieldType", data: `{"id": 1, "service_account_id": "sa"}`},
+		{name: "PEM", data: pkcs8(key)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := InspectServiceAccountKey([]byte(tt.data))
+			require.ErrorIs(t, err, ErrServiceFileInvalid)
+			require.NotErrorIs(t, err, ErrKeyCannotBeParsed)
+			require.Contains(t, err.Error(), "file is not valid JSON")
+		})
+	}
+}
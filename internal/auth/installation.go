@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"fmt"
+	"net"
+)
+
+// Installation describes iam parameters of a Yandex Cloud installation.
+type Installation struct {
+	// Name is used by LookupInstallation.
+	Name string
+	// Endpoint is the iam endpoint (host:port).
+	Endpoint string
+	// Audience is the audience of jwt tokens exchanged at Endpoint.
+	Audience string
+	// CA contains PEM encoded root certificates appended to the cert pool.
+	CA []byte
+}
+
+var (
+	// InstallationYandexCloud is the public Yandex Cloud installation.
+	InstallationYandexCloud = Installation{
+		Name:     "yandex-cloud",
+		Endpoint: DefaultEndpoint,
+		Audience: DefaultAudience,
+		CA:       ycPEM,
+	}
+	// InstallationYandexCloudKZ is the Yandex Cloud Kazakhstan installation.
+	InstallationYandexCloudKZ = Installation{
+		Name:     "yandex-cloud-kz",
+		Endpoint: "iam.api.yandexcloud.kz:443",
+		Audience: "https://iam.api.yandexcloud.kz/iam/v1/tokens",
+		CA:       ycPEM,
+	}
+)
+
+var installations = []Installation{
+	InstallationYandexCloud,
+	InstallationYandexCloudKZ,
+}
+
+// LookupInstallation returns a known installation by name.
+func LookupInstallation(name string) (Installation, bool) {
+	for _, i := range installations {
+		if i.Name == name {
+			return i, true
+		}
+	}
+
+	return Installation{}, false
+}
+
+// audienceForEndpoint returns the jwt audience expected by the iam server at endpoint.
+func audienceForEndpoint(endpoint string) string {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host = endpoint
+	}
+
+	return fmt.Sprintf("https://%s/iam/v1/tokens", host)
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallation(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	serviceKey, err := json.Marshal(map[string]string{
+		"id":                 "key-id",
+		"service_account_id": "sa-id",
+		"private_key": string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		})),
+		"endpoint": "iam.api.yandexcloud.kz:443",
+	})
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name     string
+		opts     []ClientOption
+		endpoint string
+		audience string
+	}{
+		{
+			name:     "Default",
+			opts:     []ClientOption{WithPrivateKey(key)},
+			endpoint: DefaultEndpoint,
+			audience: DefaultAudience,
+		},
+		{
+			name:     "Installation",
+			opts:     []ClientOption{WithInstallation(InstallationYandexCloudKZ)},
+			endpoint: "iam.api.yandexcloud.kz:443",
+			audience: "https://iam.api.yandexcloud.kz/iam/v1/tokens",
+		},
+		{
+			name:     "InstallationName",
+			opts:     []ClientOption{WithInstallationName("yandex-cloud-kz")},
+			endpoint: "iam.api.yandexcloud.kz:443",
+			audience: "https://iam.api.yandexcloud.kz/iam/v1/tokens",
+		},
+		{
+			name:     "ServiceKeyEndpoint",
+			opts:     []ClientOption{WithServiceKey(string(serviceKey))},
+			endpoint: "iam.api.yandexcloud.kz:443",
+			audience: "https://iam.api.yandexcloud.kz/iam/v1/tokens",
+		},
+		{
+			name:     "ServiceKeyEndpointAndAudience",
+			opts:     []ClientOption{WithServiceKey(string(serviceKey)), WithAudience("audience")},
+			endpoint: "iam.api.yandexcloud.kz:443",
+			audience: "audience",
+		},
+		{
+			name:     "ServiceKeyAndInstallation",
+			opts:     []ClientOption{WithServiceKey(string(serviceKey)), WithInstallation(InstallationYandexCloudKZ)},
+			endpoint: "iam.api.yandexcloud.kz:443",
+			audience: "https://iam.api.yandexcloud.kz/iam/v1/tokens",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient(tt.opts...)
+			require.NoError(t, err)
+			require.Equal(t, tt.endpoint, c.(*client).endpoint)
+			require.Equal(t, tt.audience, c.(*client).audience)
+		})
+	}
+
+	_, err = NewClient(WithServiceKey(string(serviceKey)), WithInstallation(InstallationYandexCloud))
+	require.ErrorIs(t, err, ErrConflictingOptions)
+
+	_, err = NewClient(WithInstallationName("unknown"))
+	require.Error(t, err)
+}
+
+func TestInstallationCertPool(t *testing.T) {
+	shared := x509.NewCertPool()
+	_, err := NewClient(WithCertPool(shared), WithInstallation(InstallationYandexCloudKZ))
+	require.ErrorIs(t, err, ErrConflictingOptions)
+	_, err = NewClient(WithInstallation(InstallationYandexCloudKZ), WithCertPool(shared))
+	require.ErrorIs(t, err, ErrConflictingOptions)
+	_, err = NewClient(WithCertPool(nil), WithInstallation(InstallationYandexCloudKZ))
+	require.ErrorIs(t, err, ErrConflictingOptions)
+	require.Empty(t, shared.Subjects()) //nolint:staticcheck // Subjects of a pool built in the test.
+
+	c := &client{}
+	require.NoError(t, WithInstallation(InstallationYandexCloudKZ)(c))
+	require.NotNil(t, c.certPool)
+}
+
+func TestCertPoolFile(t *testing.T) {
+	cert, _ := selfSignedCert(t, "iam.local")
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600,
+	))
+
+	for _, tt := range []struct {
+		name string
+		opts []ClientOption
+	}{
+		{name: "Default", opts: []ClientOption{WithCertPoolFile(caFile)}},
+		{name: "SystemAfterFile", opts: []ClientOption{WithCertPoolFile(caFile), WithSystemCertPool()}},
+		{name: "SystemBeforeFile", opts: []ClientOption{WithSystemCertPool(), WithCertPoolFile(caFile)}},
+		{
+			name: "InstallationAfterFile",
+			opts: []ClientOption{WithCertPoolFile(caFile), WithInstallation(InstallationYandexCloudKZ)},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient(tt.opts...)
+			require.NoError(t, err)
+			_, err = leaf.Verify(x509.VerifyOptions{Roots: c.(*client).certPool, DNSName: "iam.local"})
+			require.NoError(t, err)
+		})
+	}
+
+	shared := x509.NewCertPool()
+	_, err = NewClient(WithCertPoolFile(caFile), WithCertPool(shared))
+	require.ErrorIs(t, err, ErrConflictingOptions)
+	_, err = NewClient(WithCertPool(shared), WithCertPoolFile(caFile))
+	require.ErrorIs(t, err, ErrConflictingOptions)
+	require.Empty(t, shared.Subjects()) //nolint:staticcheck // Subjects of a pool built in the test.
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrPublicKeyPinMismatch = errors.New("iam server public key does not match pinned public keys")
+
+// pinPrefix is an optional prefix of a pin as used by HPKP ("sha256/<base64>").
+const pinPrefix = "sha256/"
+
+// parsePins decodes base64 encoded SHA-256 hashes of SubjectPublicKeyInfo.
+func parsePins(hashes []string) ([][sha256.Size]byte, error) {
+	pins := make([][sha256.Size]byte, 0, len(hashes))
+	for _, hash := range hashes {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(hash, pinPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("public key pin '%s' is not valid base64: %w", hash, err)
+		}
+		if len(raw) != sha256.Size {
+			return nil, fmt.Errorf("public key pin '%s' is not a SHA-256 hash", hash)
+		}
+		var pin [sha256.Size]byte
+		copy(pin[:], raw)
+		pins = append(pins, pin)
+	}
+
+	return pins, nil
+}
+
+// publicKeyPin returns SHA-256 hash of certificate SubjectPublicKeyInfo.
+func publicKeyPin(cert *x509.Certificate) [sha256.Size]byte {
+	return sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+}
+
+// verifyPins checks that at least one certificate of the verified chains (or of the presented
+// certificates if verification is skipped) matches one of pins.
+func verifyPins(cs tls.ConnectionState, pins [][sha256.Size]byte) error {
+	chains := cs.VerifiedChains
+	if len(chains) == 0 {
+		chains = [][]*x509.Certificate{cs.PeerCertificates}
+	}
+	var presented []string
+	for _, chain := range chains {
+		for _, cert := range chain {
+			hash := publicKeyPin(cert)
+			for _, pin := range pins {
+				if hash == pin {
+					return nil
+				}
+			}
+			presented = append(presented, pinPrefix+base64.StdEncoding.EncodeToString(hash[:]))
+		}
+	}
+
+	return fmt.Errorf("%w: presented %v", ErrPublicKeyPinMismatch, presented)
+}
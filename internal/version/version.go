@@ -0,0 +1,43 @@
+// Package version provides the version of ydb-go-yc module used in the binary.
+package version
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+const modulePath = "github.com/ydb-platform/ydb-go-yc"
+
+// Devel is reported when the module version is unknown (e.g. in tests or in local builds).
+const Devel = "devel"
+
+var (
+	once    sync.Once
+	version = Devel
+)
+
+// Version returns the version of ydb-go-yc module from the build info of the binary.
+func Version() string {
+	once.Do(func() {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		modules := append([]*debug.Module{&info.Main}, info.Deps...)
+		for _, m := range modules {
+			if m.Path != modulePath {
+				continue
+			}
+			if m.Replace != nil {
+				m = m.Replace
+			}
+			if m.Version != "" && m.Version != "(devel)" {
+				version = m.Version
+			}
+
+			return
+		}
+	})
+
+	return version
+}
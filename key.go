@@ -0,0 +1,22 @@
+package yc
+
+import (
+	"github.com/ydb-platform/ydb-go-yc/internal/auth"
+)
+
+var (
+	// ErrServiceFileInvalid is returned when the service account key misses id, service_account_id or
+	// private_key.
+	ErrServiceFileInvalid = auth.ErrServiceFileInvalid
+	// ErrKeyCannotBeParsed is returned when the private key is not a PEM encoded RSA key.
+	ErrKeyCannotBeParsed = auth.ErrKeyCannotBeParsed
+)
+
+// ServiceAccountKeyInfo describes the service account key file without its private part.
+type ServiceAccountKeyInfo = auth.ServiceAccountKeyInfo
+
+// InspectServiceAccountKey parses the service account key data as WithServiceKey does and describes
+// it. On error, the returned info contains the fields recognized before the failure.
+func InspectServiceAccountKey(data []byte) (*ServiceAccountKeyInfo, error) {
+	return auth.InspectServiceAccountKey(data)
+}
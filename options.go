@@ -3,19 +3,42 @@ package yc
 import (
 	"context"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/ydb-platform/ydb-go-sdk/v3"
 	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+	"google.golang.org/grpc"
 
 	yc "github.com/ydb-platform/ydb-go-yc-metadata"
 	"github.com/ydb-platform/ydb-go-yc/internal/auth"
+	"github.com/ydb-platform/ydb-go-yc/trace"
 )
 
 type ClientOption = auth.ClientOption
 
+// Balancing defines the order in which several iam endpoints provided with WithEndpoints are tried.
+type Balancing = auth.Balancing
+
+const (
+	// BalancingPriority tries endpoints in the listed order, the next endpoint is used only
+	// if the previous ones fail or are ejected.
+	BalancingPriority = auth.BalancingPriority
+	// BalancingRoundRobin starts each request from the next endpoint.
+	BalancingRoundRobin = auth.BalancingRoundRobin
+)
+
+// ErrConflictingOptions is returned by NewClient when two options set the same client field
+// to different values, e.g. WithServiceFile together with WithPrivateKey.
+var ErrConflictingOptions = auth.ErrConflictingOptions
+
+// ErrPublicKeyPinMismatch is returned from Token when the iam server certificate chain does not
+// match keys provided with WithPinnedPublicKeys and WithBackupPinnedPublicKeys.
+var ErrPublicKeyPinMismatch = auth.ErrPublicKeyPinMismatch
+
 func NewInstanceServiceAccount(
 	opts ...yc.InstanceServiceAccountCredentialsOption,
 ) *yc.InstanceServiceAccountCredentials {
@@ -77,27 +100,112 @@ func WithFallbackCredentials(fallback credentials.Credentials) ClientOption {
 	return auth.WithFallbackCredentials(fallback)
 }
 
+// WithOAuthToken makes client exchange the OAuth token of Yandex account for iam tokens instead of
+// the jwt signed by the service account key.
+func WithOAuthToken(oauthToken string) ClientOption {
+	return auth.WithOAuthToken(oauthToken)
+}
+
+// WithImpersonatedServiceAccount makes client return tokens of the service account serviceAccountID
+// issued by IamTokenService.CreateForServiceAccount to the identity of the client. If the client can not
+// be created, the service account is impersonated by credentials of WithFallbackCredentials.
+func WithImpersonatedServiceAccount(serviceAccountID string) ClientOption {
+	return auth.WithImpersonatedServiceAccount(serviceAccountID)
+}
+
 // WithEndpoint set provided endpoint.
 func WithEndpoint(endpoint string) ClientOption {
 	return auth.WithEndpoint(endpoint)
 }
 
+// WithEndpoints set several iam endpoints. Endpoints are tried in the order defined by WithBalancing
+// and an endpoint is ejected for a cooldown after consecutive failures (see WithEndpointCircuitBreaker).
+// Only failures of the endpoint itself (unavailability, timeouts, etc.) make the client try
+// the next endpoint.
+func WithEndpoints(endpoints ...string) ClientOption {
+	return auth.WithEndpoints(endpoints...)
+}
+
+// WithBalancing set the order in which endpoints provided with WithEndpoints are tried.
+// Defaults to BalancingPriority.
+func WithBalancing(balancing Balancing) ClientOption {
+	return auth.WithBalancing(balancing)
+}
+
+// WithEndpointCircuitBreaker set circuit breaker parameters of endpoints provided with WithEndpoints:
+// an endpoint is ejected for cooldown after maxFailures consecutive failures.
+// Defaults to 3 failures and 30 seconds.
+func WithEndpointCircuitBreaker(maxFailures int, cooldown time.Duration) ClientOption {
+	return auth.WithEndpointCircuitBreaker(maxFailures, cooldown)
+}
+
+// WithTrace set trace of the client events. Several traces are composed.
+func WithTrace(t trace.Trace) ClientOption {
+	return auth.WithTrace(t)
+}
+
 // WithDefaultEndpoint set endpoint with default value.
 func WithDefaultEndpoint() ClientOption {
 	return auth.WithDefaultEndpoint()
 }
 
+// Installation describes iam parameters of a Yandex Cloud installation.
+type Installation = auth.Installation
+
+var (
+	// InstallationYandexCloud is the public Yandex Cloud installation.
+	InstallationYandexCloud = auth.InstallationYandexCloud
+	// InstallationYandexCloudKZ is the Yandex Cloud Kazakhstan installation.
+	InstallationYandexCloudKZ = auth.InstallationYandexCloudKZ
+)
+
+// WithInstallation set endpoint, audience and root certificates of the provided installation.
+// Root certificates are system ones extended with the installation CA and certificates of
+// WithCertPoolFile, they can not be combined with WithCertPool and WithSystemCertPool.
+//
+// The endpoint field of a service account key also defines the audience, unless WithAudience
+// or WithInstallation is provided.
+func WithInstallation(installation Installation) ClientOption {
+	return auth.WithInstallation(installation)
+}
+
+// WithInstallationName set endpoint, audience and root certificates of the known installation
+// ("yandex-cloud", "yandex-cloud-kz").
+func WithInstallationName(name string) ClientOption {
+	return auth.WithInstallationName(name)
+}
+
+// WithEndpointDiscovery makes client resolve iam endpoint with ApiEndpointService.List at discoveryEndpoint
+// ("api.cloud.yandex.net:443" if empty) on the first Token call. Discovered endpoints are cached by the client
+// for an hour. Unless audience is provided, it follows the discovered endpoint.
+//
+// Private installations which publish their own endpoint list are supported by providing
+// their discovery endpoint.
+func WithEndpointDiscovery(discoveryEndpoint string) ClientOption {
+	return auth.WithEndpointDiscovery(discoveryEndpoint)
+}
+
+// DiscoverEndpoint returns the address of service ("iam", "ydb", etc.) listed by ApiEndpointService.
+// The discovery endpoint and connection parameters are taken from opts (e.g. WithEndpointDiscovery,
+// WithCertPoolFile, WithProxy).
+func DiscoverEndpoint(ctx context.Context, service string, opts ...ClientOption) (string, error) {
+	return auth.DiscoverEndpoint(ctx, service, opts...)
+}
+
 // WithSourceInfo set sourceInfo
 func WithSourceInfo(sourceInfo string) ClientOption {
 	return auth.WithSourceInfo(sourceInfo)
 }
 
-// WithCertPool set provided certPool.
+// WithCertPool set provided certPool. The pool is not modified, so it can not be combined with
+// WithCertPoolFile.
 func WithCertPool(certPool *x509.CertPool) ClientOption {
 	return auth.WithCertPool(certPool)
 }
 
-// WithCertPoolFile try set root certPool from provided cert file path.
+// WithCertPoolFile appends certificates from provided cert file path to the root cert pool: the default
+// one, the one of WithSystemCertPool or WithInstallation regardless of the order of options.
+// The pool of WithCertPool is not modified, so the options can not be combined.
 func WithCertPoolFile(caFile string) ClientOption {
 	return auth.WithCertPoolFile(caFile)
 }
@@ -107,12 +215,83 @@ func WithSystemCertPool() ClientOption {
 	return auth.WithSystemCertPool()
 }
 
+// WithServerName overrides the host name used to verify the iam server certificate.
+// By default, the host of the endpoint is used.
+func WithServerName(serverName string) ClientOption {
+	return auth.WithServerName(serverName)
+}
+
+// WithMinTLSVersion set minimal TLS version (tls.VersionTLS12, tls.VersionTLS13, etc.) accepted
+// from the iam server. Defaults to tls.VersionTLS12.
+func WithMinTLSVersion(version uint16) ClientOption {
+	return auth.WithMinTLSVersion(version)
+}
+
+// WithPinnedPublicKeys pins the iam server certificate chain by base64 encoded SHA-256 hashes of
+// SubjectPublicKeyInfo (optionally prefixed with "sha256/"). The pins are checked in addition to
+// the normal certificate verification: the connection fails with ErrPublicKeyPinMismatch unless
+// at least one certificate of the verified chain matches one of pinned or backup pinned keys.
+// At least one hash is required, empty list is an error rather than disabled pinning.
+//
+// The hash of a certificate can be computed with
+//
+//	openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func WithPinnedPublicKeys(hashes ...string) ClientOption {
+	return auth.WithPinnedPublicKeys(hashes...)
+}
+
+// WithBackupPinnedPublicKeys set backup pins accepted along with WithPinnedPublicKeys, e.g. keys
+// of the next CA during CA rotation. At least one hash is required.
+func WithBackupPinnedPublicKeys(hashes ...string) ClientOption {
+	return auth.WithBackupPinnedPublicKeys(hashes...)
+}
+
+// WithClientCertificate set client certificate presented to the iam server from provided PEM files.
+// The files are reloaded on the next connection after they change, so certificates can be rotated
+// without restarting the application.
+func WithClientCertificate(certFile, keyFile string) ClientOption {
+	return auth.WithClientCertificate(certFile, keyFile)
+}
+
+// WithClientCertificateFunc set callback which returns client certificate presented to the iam server.
+// The callback is called on each TLS handshake.
+func WithClientCertificateFunc(
+	getCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error),
+) ClientOption {
+	return auth.WithClientCertificateFunc(getCertificate)
+}
+
+// WithProxy set proxy used to connect to the iam server. Supported schemes are http and https
+// (HTTP CONNECT tunnel), socks5 and socks5h. Credentials may be provided in the url user info.
+//
+// Without this option the proxy is taken from HTTPS_PROXY and NO_PROXY environment variables.
+func WithProxy(proxyURL string) ClientOption {
+	return auth.WithProxy(proxyURL)
+}
+
+// WithContextDialer set dialer used to connect to the iam server or to the proxy.
+func WithContextDialer(dialer func(ctx context.Context, addr string) (net.Conn, error)) ClientOption {
+	return auth.WithContextDialer(dialer)
+}
+
+// WithGRPCDialOptions appends options to the options of the iam connection (keepalive parameters,
+// interceptors, user agent, etc.). The options are applied after the transport security and dialer
+// options of the client, so they take precedence.
+//
+// By default, the connection has "ydb-go-yc/<version>" user agent.
+func WithGRPCDialOptions(opts ...grpc.DialOption) ClientOption {
+	return auth.WithGRPCDialOptions(opts...)
+}
+
 // WithInsecureSkipVerify set insecureSkipVerify to true which force client accepts any TLS certificate
 // presented by the iam server and any host name in that certificate.
 //
-// If insecureSkipVerify is set, then certPool field is not used.
+// If insecureSkipVerify is set, then certPool field is not used. NewClient reports disabled
+// verification with trace.Trace.OnInsecureSkipVerify (see WithTrace), or logs a warning with
+// the standard logger if the trace is not set.
 //
-// This should be used only for testing purposes.
+// This should be used only for testing purposes. WithInsecureSkipVerify(true) can not be combined
+// with cert pool options.
 func WithInsecureSkipVerify(insecure bool) ClientOption {
 	return auth.WithInsecureSkipVerify(insecure)
 }
@@ -132,6 +311,45 @@ func WithTokenTTL(tokenTTL time.Duration) ClientOption {
 	return auth.WithTokenTTL(tokenTTL)
 }
 
+// TokenExchanger exchanges the jwt signed by the client for an iam token, see WithTransport.
+type TokenExchanger = auth.TokenExchanger
+
+// TokenExchangerFunc is an adapter to use ordinary functions as TokenExchanger.
+type TokenExchangerFunc = auth.TokenExchangerFunc
+
+// ImpersonationExchanger issues tokens of the service account to the holder of the iam token. TokenExchanger
+// of WithTransport used for impersonation (WithImpersonatedServiceAccount, NewImpersonator) must implement it.
+type ImpersonationExchanger = auth.ImpersonationExchanger
+
+// WithTransport makes client exchange jwt for iam tokens with exchanger instead of grpc call to
+// the iam endpoint, e.g. through an internal gateway or a stub in tests. The client still signs jwt
+// and caches tokens. Impersonated tokens are issued with exchanger too, see ImpersonationExchanger.
+func WithTransport(exchanger TokenExchanger) ClientOption {
+	return auth.WithTransport(exchanger)
+}
+
+// Clock provides the current time to the client, see WithClock. If the clock also implements
+// After(time.Duration) <-chan time.Time (e.g. clockwork.FakeClock), the retry backoff is waited with it.
+type Clock = auth.Clock
+
+// WithClock set the clock used to issue jwt, to expire cached tokens and to wait retry backoff,
+// e.g. a fake clock in tests.
+func WithClock(clock Clock) ClientOption {
+	return auth.WithClock(clock)
+}
+
+// WithRetry makes client retry token creation up to attempts times (including the first one) if the
+// iam endpoint fails with unavailability, timeout, etc. The n-th retry is made after n*backoff.
+func WithRetry(attempts int, backoff time.Duration) ClientOption {
+	return auth.WithRetry(attempts, backoff)
+}
+
+// WithRefreshRatio set the part of the token lifetime after which the cached token is refreshed.
+// Defaults to 0.5.
+func WithRefreshRatio(ratio float64) ClientOption {
+	return auth.WithRefreshRatio(ratio)
+}
+
 // WithAudience set provided audience.
 func WithAudience(audience string) ClientOption {
 	return auth.WithAudience(audience)
@@ -150,6 +368,7 @@ func WithPrivateKeyFile(path string) ClientOption {
 // WithServiceFile try set key, keyID, issuer from provided service account file path.
 //
 // Do not mix this option with WithKeyID, WithIssuer and key options (WithPrivateKey, WithPrivateKeyFile, etc).
+// Mixing them makes NewClient return ErrConflictingOptions.
 func WithServiceFile(path string) ClientOption {
 	return auth.WithServiceFile(path)
 }
@@ -157,6 +376,7 @@ func WithServiceFile(path string) ClientOption {
 // WithServiceKey try set key, keyID, issuer from provided service account key.
 //
 // Do not mix this option with WithKeyID, WithIssuer and key options (WithPrivateKey, WithPrivateKeyFile, etc).
+// Mixing them makes NewClient return ErrConflictingOptions.
 func WithServiceKey(json string) ClientOption {
 	return auth.WithServiceKey(json)
 }
@@ -0,0 +1,166 @@
+// Package trace provides callbacks for events of ydb-go-yc credentials.
+package trace
+
+import (
+	"time"
+)
+
+// Trace contains callbacks for events of the iam client. Nil callbacks are skipped.
+type Trace struct {
+	// OnEndpointEjected is called when the iam endpoint is ejected from balancing after
+	// consecutive failures.
+	OnEndpointEjected func(EndpointEjectedInfo)
+	// OnEndpointRestored is called when the ejected iam endpoint responds successfully again.
+	OnEndpointRestored func(EndpointRestoredInfo)
+	// OnTokenExpiring is called once per token when the static iam token is close to its expiration.
+	OnTokenExpiring func(TokenExpiringInfo)
+	// OnTokenRefreshed is called when the iam client receives a new token or fails to.
+	OnTokenRefreshed func(TokenRefreshedInfo)
+	// OnInsecureSkipVerify is called by NewClient when TLS certificate verification of the iam
+	// endpoint is disabled with WithInsecureSkipVerify(true).
+	OnInsecureSkipVerify func(InsecureSkipVerifyInfo)
+}
+
+type (
+	EndpointEjectedInfo struct {
+		Endpoint string
+		// Failures is the number of consecutive failures of the endpoint.
+		Failures int
+		// Until is the moment when the endpoint will be tried again.
+		Until time.Time
+		// Error is the last error of the endpoint.
+		Error error
+	}
+	EndpointRestoredInfo struct {
+		Endpoint string
+	}
+	TokenExpiringInfo struct {
+		// Source describes where the token is read from.
+		Source    string
+		ExpiresAt time.Time
+	}
+	TokenRefreshedInfo struct {
+		// ExpiresAt is the expiration of the new token reported by iam, zero on error.
+		ExpiresAt time.Time
+		Error     error
+	}
+	InsecureSkipVerifyInfo struct {
+		Endpoint string
+	}
+)
+
+// Compose returns a new Trace which has callbacks composed both from t and x.
+func (t Trace) Compose(x Trace) (ret Trace) {
+	switch {
+	case t.OnEndpointEjected == nil:
+		ret.OnEndpointEjected = x.OnEndpointEjected
+	case x.OnEndpointEjected == nil:
+		ret.OnEndpointEjected = t.OnEndpointEjected
+	default:
+		h1, h2 := t.OnEndpointEjected, x.OnEndpointEjected
+		ret.OnEndpointEjected = func(info EndpointEjectedInfo) {
+			h1(info)
+			h2(info)
+		}
+	}
+	switch {
+	case t.OnEndpointRestored == nil:
+		ret.OnEndpointRestored = x.OnEndpointRestored
+	case x.OnEndpointRestored == nil:
+		ret.OnEndpointRestored = t.OnEndpointRestored
+	default:
+		h1, h2 := t.OnEndpointRestored, x.OnEndpointRestored
+		ret.OnEndpointRestored = func(info EndpointRestoredInfo) {
+			h1(info)
+			h2(info)
+		}
+	}
+	switch {
+	case t.OnTokenExpiring == nil:
+		ret.OnTokenExpiring = x.OnTokenExpiring
+	case x.OnTokenExpiring == nil:
+		ret.OnTokenExpiring = t.OnTokenExpiring
+	default:
+		h1, h2 := t.OnTokenExpiring, x.OnTokenExpiring
+		ret.OnTokenExpiring = func(info TokenExpiringInfo) {
+			h1(info)
+			h2(info)
+		}
+	}
+	switch {
+	case t.OnTokenRefreshed == nil:
+		ret.OnTokenRefreshed = x.OnTokenRefreshed
+	case x.OnTokenRefreshed == nil:
+		ret.OnTokenRefreshed = t.OnTokenRefreshed
+	default:
+		h1, h2 := t.OnTokenRefreshed, x.OnTokenRefreshed
+		ret.OnTokenRefreshed = func(info TokenRefreshedInfo) {
+			h1(info)
+			h2(info)
+		}
+	}
+	switch {
+	case t.OnInsecureSkipVerify == nil:
+		ret.OnInsecureSkipVerify = x.OnInsecureSkipVerify
+	case x.OnInsecureSkipVerify == nil:
+		ret.OnInsecureSkipVerify = t.OnInsecureSkipVerify
+	default:
+		h1, h2 := t.OnInsecureSkipVerify, x.OnInsecureSkipVerify
+		ret.OnInsecureSkipVerify = func(info InsecureSkipVerifyInfo) {
+			h1(info)
+			h2(info)
+		}
+	}
+
+	return ret
+}
+
+// Warning: only for internal usage inside ydb-go-yc
+func TraceOnEndpointEjected(t Trace, endpoint string, failures int, until time.Time, err error) {
+	if fn := t.OnEndpointEjected; fn != nil {
+		fn(EndpointEjectedInfo{
+			Endpoint: endpoint,
+			Failures: failures,
+			Until:    until,
+			Error:    err,
+		})
+	}
+}
+
+// Warning: only for internal usage inside ydb-go-yc
+func TraceOnEndpointRestored(t Trace, endpoint string) {
+	if fn := t.OnEndpointRestored; fn != nil {
+		fn(EndpointRestoredInfo{
+			Endpoint: endpoint,
+		})
+	}
+}
+
+// Warning: only for internal usage inside ydb-go-yc
+func TraceOnTokenExpiring(t Trace, source string, expiresAt time.Time) {
+	if fn := t.OnTokenExpiring; fn != nil {
+		fn(TokenExpiringInfo{
+			Source:    source,
+			ExpiresAt: expiresAt,
+		})
+	}
+}
+
+// Warning: only for internal usage inside ydb-go-yc
+func TraceOnTokenRefreshed(t Trace, expiresAt time.Time, err error) {
+	if fn := t.OnTokenRefreshed; fn != nil {
+		fn(TokenRefreshedInfo{
+			ExpiresAt: expiresAt,
+			Error:     err,
+		})
+	}
+}
+
+// Warning: only for internal usage inside ydb-go-yc
+func TraceOnInsecureSkipVerify(t Trace, endpoint string) {
+	if fn := t.OnInsecureSkipVerify; fn != nil {
+		fn(InsecureSkipVerifyInfo{
+			Endpoint: endpoint,
+		})
+	}
+}
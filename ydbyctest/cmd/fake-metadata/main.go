@@ -0,0 +1,67 @@
+// Command fake-metadata runs ydbyctest.MetadataService standalone, so code using metadata
+// credentials can be run offline:
+//
+//	fake-metadata -listen 127.0.0.1:6770 -attribute hostname=dev
+//
+// and use yc.WithMetadataCredentialsURL("http://127.0.0.1:6770/computeMetadata/v1/instance/service-accounts/default/token").
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ydb-platform/ydb-go-yc/ydbyctest"
+)
+
+type attributes []ydbyctest.MetadataServiceOption
+
+func (a *attributes) String() string {
+	return ""
+}
+
+func (a *attributes) Set(s string) error {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("attribute must be in path=value form, got '%s'", s)
+	}
+	*a = append(*a, ydbyctest.WithMetadataAttribute(kv[0], kv[1]))
+
+	return nil
+}
+
+func main() {
+	var (
+		listen   = flag.String("listen", "127.0.0.1:6770", "address to listen on")
+		token    = flag.String("token", "", "token to serve, fake tokens are issued if empty")
+		lifetime = flag.Duration("lifetime", ydbyctest.DefaultMetadataTokenLifetime, "lifetime of tokens")
+		attrs    attributes
+	)
+	flag.Var(&attrs, "attribute", "instance attribute in path=value form, e.g. hostname=dev (repeatable)")
+	flag.Parse()
+
+	opts := append(attrs, ydbyctest.WithMetadataTokenLifetime(*lifetime))
+	if *token != "" {
+		opts = append(opts, ydbyctest.WithMetadataTokenSource(
+			func(context.Context) (string, time.Time, error) {
+				return *token, time.Now().Add(*lifetime), nil
+			},
+		))
+	}
+
+	log.Printf("serving fake metadata on http://%s%s", *listen, ydbyctest.MetadataTokenPath)
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           ydbyctest.NewMetadataService(opts...),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if err := srv.ListenAndServe(); err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
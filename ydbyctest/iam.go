@@ -0,0 +1,384 @@
+// Package ydbyctest provides in-process fakes of Yandex Cloud services for testing code which uses
+// ydb-go-yc credentials.
+package ydbyctest
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	v1 "github.com/yandex-cloud/go-genproto/yandex/cloud/iam/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/ydb-platform/ydb-go-yc/internal/auth"
+)
+
+// Defaults of IAMTokenService.
+const (
+	DefaultAudience      = auth.DefaultAudience
+	DefaultTokenLifetime = 12 * time.Hour
+)
+
+// Request is a request received by IAMTokenService.
+type Request struct {
+	Time time.Time
+	// Method is "Create" or "CreateForServiceAccount".
+	Method string
+	// JWT is the jwt of Create request.
+	JWT string
+	// KeyID is the kid of the jwt.
+	KeyID string
+	// ServiceAccountID is the subject of the issued token: the issuer of the jwt, the user of
+	// the OAuth token or the impersonated service account.
+	ServiceAccountID string
+	// Token is the issued iam token, empty if the request failed.
+	Token string
+	Err   error
+}
+
+type registeredKey struct {
+	serviceAccountID string
+	publicKey        *rsa.PublicKey
+}
+
+type issuedToken struct {
+	serviceAccountID string
+	expiresAt        time.Time
+}
+
+// IAMTokenService is a fake of IamTokenService. It issues tokens for jwt signed with PS256 by one of
+// the registered keys and checks kid, iss, aud and exp claims as IAM does.
+type IAMTokenService struct {
+	v1.UnimplementedIamTokenServiceServer
+
+	audience string
+	now      func() time.Time
+
+	mu       sync.Mutex
+	lifetime time.Duration
+	keys     map[string]registeredKey
+	// oauthTokens maps OAuth tokens to user ids.
+	oauthTokens map[string]string
+	tokens      map[string]issuedToken
+	errs        []error // returned by the next requests
+	err         error   // returned by all requests if set
+	latency     time.Duration
+	requests    []Request
+}
+
+type IAMTokenServiceOption func(*IAMTokenService)
+
+// WithAudience set the audience expected in jwt, DefaultAudience by default.
+func WithAudience(audience string) IAMTokenServiceOption {
+	return func(s *IAMTokenService) {
+		s.audience = audience
+	}
+}
+
+// WithTokenLifetime set the lifetime of issued tokens, DefaultTokenLifetime by default.
+func WithTokenLifetime(lifetime time.Duration) IAMTokenServiceOption {
+	return func(s *IAMTokenService) {
+		s.lifetime = lifetime
+	}
+}
+
+// WithNow set the source of the current time used to check jwt and expiration of tokens.
+func WithNow(now func() time.Time) IAMTokenServiceOption {
+	return func(s *IAMTokenService) {
+		s.now = now
+	}
+}
+
+func NewIAMTokenService(opts ...IAMTokenServiceOption) *IAMTokenService {
+	s := &IAMTokenService{
+		audience:    DefaultAudience,
+		now:         time.Now,
+		lifetime:    DefaultTokenLifetime,
+		keys:        make(map[string]registeredKey),
+		oauthTokens: make(map[string]string),
+		tokens:      make(map[string]issuedToken),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Register registers the service on the grpc server.
+func (s *IAMTokenService) Register(srv *grpc.Server) {
+	v1.RegisterIamTokenServiceServer(srv, s)
+}
+
+// AddKey registers the public key of the service account. Jwt with kid equal to keyID must be
+// signed with the corresponding private key.
+func (s *IAMTokenService) AddKey(keyID, serviceAccountID string, publicKey *rsa.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[keyID] = registeredKey{
+		serviceAccountID: serviceAccountID,
+		publicKey:        publicKey,
+	}
+}
+
+// NewServiceAccountKey generates a key of the service account and registers it.
+func (s *IAMTokenService) NewServiceAccountKey(serviceAccountID string) (*ServiceAccountKey, error) {
+	key, err := NewServiceAccountKey(serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	s.AddKey(key.ID, serviceAccountID, &key.PrivateKey.PublicKey)
+
+	return key, nil
+}
+
+// AddOAuthToken registers the OAuth token of the Yandex account userID.
+func (s *IAMTokenService) AddOAuthToken(oauthToken, userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.oauthTokens[oauthToken] = userID
+}
+
+// RemoveKey unregisters the key, e.g. to emulate key deletion.
+func (s *IAMTokenService) RemoveKey(keyID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, keyID)
+}
+
+// SetTokenLifetime changes the lifetime of tokens issued after the call.
+func (s *IAMTokenService) SetTokenLifetime(lifetime time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lifetime = lifetime
+}
+
+// FailNext makes the next len(errs) requests fail with errs in order. Use status errors to emulate
+// grpc failures, e.g. status.Error(codes.Unavailable, "").
+func (s *IAMTokenService) FailNext(errs ...error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errs = append(s.errs, errs...)
+}
+
+// SetError makes all requests fail with err until it is reset with nil.
+func (s *IAMTokenService) SetError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+}
+
+// SetLatency delays responses, the delay is interrupted if the request is canceled.
+func (s *IAMTokenService) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = latency
+}
+
+// Requests returns the received requests.
+func (s *IAMTokenService) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset clears the received requests and injected errors.
+func (s *IAMTokenService) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = nil
+	s.errs = nil
+	s.err = nil
+	s.latency = 0
+}
+
+// ServiceAccountID returns the service account of the token if it was issued by the service
+// and is not expired.
+func (s *IAMTokenService) ServiceAccountID(token string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[token]
+	if !ok || !s.now().Before(t.expiresAt) {
+		return "", false
+	}
+
+	return t.serviceAccountID, true
+}
+
+func (s *IAMTokenService) Create(ctx context.Context, req *v1.CreateIamTokenRequest) (
+	*v1.CreateIamTokenResponse, error,
+) {
+	r := Request{
+		Method: "Create",
+		JWT:    req.GetJwt(),
+	}
+
+	return s.handle(ctx, &r, func() error {
+		if oauthToken := req.GetYandexPassportOauthToken(); oauthToken != "" {
+			s.mu.Lock()
+			userID, ok := s.oauthTokens[oauthToken]
+			s.mu.Unlock()
+			if !ok {
+				return status.Error(codes.Unauthenticated, "unknown OAuth token")
+			}
+			r.ServiceAccountID = userID
+
+			return nil
+		}
+		if req.GetJwt() == "" {
+			return status.Error(codes.InvalidArgument, "jwt or OAuth token identity is required")
+		}
+		var err error
+		r.KeyID, r.ServiceAccountID, err = s.verify(req.GetJwt())
+
+		return err
+	})
+}
+
+func (s *IAMTokenService) CreateForServiceAccount(
+	ctx context.Context, req *v1.CreateIamTokenForServiceAccountRequest,
+) (*v1.CreateIamTokenResponse, error) {
+	r := Request{
+		Method:           "CreateForServiceAccount",
+		ServiceAccountID: req.GetServiceAccountId(),
+	}
+
+	return s.handle(ctx, &r, func() error {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) != 1 || !strings.HasPrefix(values[0], "Bearer ") {
+			return status.Error(codes.Unauthenticated, "authorization header is required")
+		}
+		if _, ok := s.ServiceAccountID(strings.TrimPrefix(values[0], "Bearer ")); !ok {
+			return status.Error(codes.Unauthenticated, "the token is invalid or expired")
+		}
+		if req.GetServiceAccountId() == "" {
+			return status.Error(codes.InvalidArgument, "service account id is required")
+		}
+
+		return nil
+	})
+}
+
+// handle records the request, injects errors and latency, validates the request and issues token.
+func (s *IAMTokenService) handle(ctx context.Context, r *Request, validate func() error) (
+	*v1.CreateIamTokenResponse, error,
+) {
+	r.Time = s.now()
+	s.mu.Lock()
+	latency, err := s.latency, s.err
+	if len(s.errs) > 0 {
+		err = s.errs[0]
+		s.errs = s.errs[1:]
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = status.FromContextError(ctx.Err()).Err()
+		case <-timer.C:
+		}
+	}
+	if err == nil {
+		err = validate()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res *v1.CreateIamTokenResponse
+	if err == nil {
+		expiresAt := s.now().Add(s.lifetime)
+		r.Token = fmt.Sprintf("t1.fake.%s.%d", r.ServiceAccountID, len(s.tokens)+1)
+		s.tokens[r.Token] = issuedToken{
+			serviceAccountID: r.ServiceAccountID,
+			expiresAt:        expiresAt,
+		}
+		res = &v1.CreateIamTokenResponse{
+			IamToken:  r.Token,
+			ExpiresAt: timestamppb.New(expiresAt),
+		}
+	}
+	r.Err = err
+	s.requests = append(s.requests, *r)
+
+	return res, err
+}
+
+// ps256 verifies PS256 signatures with salt length equal to the hash size as IAM does. Stock
+// jwt.SigningMethodPS256 accepts any salt length.
+var ps256 = &jwt.SigningMethodRSAPSS{
+	SigningMethodRSA: jwt.SigningMethodPS256.SigningMethodRSA,
+	Options: &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+	},
+	VerifyOptions: &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+	},
+}
+
+// verify checks the jwt and returns its key id and issuer.
+func (s *IAMTokenService) verify(token string) (keyID, serviceAccountID string, _ error) {
+	var (
+		claims    jwt.RegisteredClaims
+		publicKey interface{}
+	)
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{ps256.Alg()}), jwt.WithoutClaimsValidation())
+	parsed, err := parser.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		keyID, _ = t.Header["kid"].(string)
+		s.mu.Lock()
+		key, ok := s.keys[keyID]
+		s.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown key id '%s'", keyID)
+		}
+		serviceAccountID = key.serviceAccountID
+		publicKey = key.publicKey
+
+		return key.publicKey, nil
+	})
+	if err == nil {
+		err = ps256.Verify(strings.Join(strings.Split(parsed.Raw, ".")[:2], "."), parsed.Signature, publicKey)
+	}
+	if err != nil {
+		return keyID, "", status.Errorf(codes.Unauthenticated, "invalid jwt: %v", err)
+	}
+	now := s.now()
+	switch {
+	case claims.Issuer != serviceAccountID:
+		err = fmt.Errorf("issuer '%s' is not the owner of key '%s'", claims.Issuer, keyID)
+	case !claims.VerifyAudience(s.audience, true):
+		err = fmt.Errorf("audience %v does not contain '%s'", claims.Audience, s.audience)
+	case claims.ExpiresAt == nil || !now.Before(claims.ExpiresAt.Time):
+		err = errors.New("jwt is expired or has no exp claim")
+	case claims.IssuedAt != nil && claims.IssuedAt.Time.After(now.Add(time.Minute)):
+		err = errors.New("jwt is issued in the future")
+	}
+	if err != nil {
+		return keyID, "", status.Errorf(codes.Unauthenticated, "invalid jwt: %v", err)
+	}
+
+	return keyID, serviceAccountID, nil
+}
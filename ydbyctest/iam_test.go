@@ -0,0 +1,149 @@
+package ydbyctest
+
+import (
+	"context"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+	v1 "github.com/yandex-cloud/go-genproto/yandex/cloud/iam/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	yc "github.com/ydb-platform/ydb-go-yc"
+	"github.com/ydb-platform/ydb-go-yc/trace"
+)
+
+func TestIAMTokenService(t *testing.T) {
+	iam := NewIAMTokenService(WithTokenLifetime(time.Hour))
+	srv, err := NewServer(iam.Register)
+	require.NoError(t, err)
+	defer func() {
+		_ = srv.Close()
+	}()
+	key, err := iam.NewServiceAccountKey("sa-1")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	creds, err := yc.NewClient(append(srv.ClientOptions(), yc.WithServiceKey(key.JSON()))...)
+	require.NoError(t, err)
+	token, err := creds.Token(ctx)
+	require.NoError(t, err)
+	id, ok := iam.ServiceAccountID(token)
+	require.True(t, ok)
+	require.Equal(t, "sa-1", id)
+
+	requests := iam.Requests()
+	require.Len(t, requests, 1)
+	require.Equal(t, "Create", requests[0].Method)
+	require.Equal(t, key.ID, requests[0].KeyID)
+	require.Equal(t, token, requests[0].Token)
+
+	t.Run("WrongAudience", func(t *testing.T) {
+		creds, err := yc.NewClient(append(srv.ClientOptions(),
+			yc.WithServiceKey(key.JSON()), yc.WithAudience("https://other"),
+		)...)
+		require.NoError(t, err)
+		_, err = creds.Token(ctx)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("SaltLength", func(t *testing.T) {
+		sign := func(saltLength int) string {
+			method := &jwt.SigningMethodRSAPSS{
+				SigningMethodRSA: jwt.SigningMethodPS256.SigningMethodRSA,
+				Options:          &rsa.PSSOptions{SaltLength: saltLength},
+			}
+			now := time.Now()
+			token := jwt.NewWithClaims(method, jwt.RegisteredClaims{
+				Issuer:    key.ServiceAccountID,
+				Audience:  jwt.ClaimStrings{DefaultAudience},
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			})
+			token.Header["kid"] = key.ID
+			signed, err := token.SignedString(key.PrivateKey)
+			require.NoError(t, err)
+
+			return signed
+		}
+		create := func(signed string) error {
+			_, err := iam.Create(ctx, &v1.CreateIamTokenRequest{
+				Identity: &v1.CreateIamTokenRequest_Jwt{Jwt: signed},
+			})
+
+			return err
+		}
+		require.NoError(t, create(sign(rsa.PSSSaltLengthEqualsHash)))
+		// IAM rejects signatures with salt length other than the hash size.
+		require.Equal(t, codes.Unauthenticated, status.Code(create(sign(rsa.PSSSaltLengthAuto))))
+	})
+
+	t.Run("UnknownKey", func(t *testing.T) {
+		other, err := NewServiceAccountKey("sa-1")
+		require.NoError(t, err)
+		creds, err := yc.NewClient(append(srv.ClientOptions(), yc.WithServiceKey(other.JSON()))...)
+		require.NoError(t, err)
+		_, err = creds.Token(ctx)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("InjectedErrors", func(t *testing.T) {
+		iam.Reset()
+		iam.FailNext(status.Error(codes.Unavailable, "injected"))
+		creds, err := yc.NewClient(append(srv.ClientOptions(),
+			yc.WithServiceKey(key.JSON()), yc.WithRetry(2, time.Millisecond),
+		)...)
+		require.NoError(t, err)
+		_, err = creds.Token(ctx)
+		require.NoError(t, err)
+		requests := iam.Requests()
+		require.Len(t, requests, 2)
+		require.Equal(t, codes.Unavailable, status.Code(requests[0].Err))
+	})
+
+	t.Run("Latency", func(t *testing.T) {
+		iam.SetLatency(time.Minute)
+		defer iam.SetLatency(0)
+		creds, err := yc.NewClient(append(srv.ClientOptions(), yc.WithServiceKey(key.JSON()))...)
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err = creds.Token(ctx)
+		require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	})
+
+	t.Run("OAuth", func(t *testing.T) {
+		iam.AddOAuthToken("y0_oauth", "user-1")
+		var expiresAt time.Time
+		creds, err := yc.NewClient(append(srv.ClientOptions(),
+			yc.WithOAuthToken("y0_oauth"),
+			yc.WithTrace(trace.Trace{
+				OnTokenRefreshed: func(info trace.TokenRefreshedInfo) {
+					expiresAt = info.ExpiresAt
+				},
+			}),
+		)...)
+		require.NoError(t, err)
+		token, err := creds.Token(ctx)
+		require.NoError(t, err)
+		id, ok := iam.ServiceAccountID(token)
+		require.True(t, ok)
+		require.Equal(t, "user-1", id)
+		require.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+	})
+
+	t.Run("Impersonation", func(t *testing.T) {
+		creds, err := yc.NewClient(append(srv.ClientOptions(),
+			yc.WithServiceKey(key.JSON()), yc.WithImpersonatedServiceAccount("sa-2"),
+		)...)
+		require.NoError(t, err)
+		token, err := creds.Token(ctx)
+		require.NoError(t, err)
+		id, ok := iam.ServiceAccountID(token)
+		require.True(t, ok)
+		require.Equal(t, "sa-2", id)
+	})
+}
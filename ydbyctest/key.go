@@ -0,0 +1,62 @@
+package ydbyctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"sync/atomic"
+)
+
+var keyCounter int64
+
+// ServiceAccountKey is an authorized key of a service account generated for tests.
+type ServiceAccountKey struct {
+	ID               string
+	ServiceAccountID string
+	PrivateKey       *rsa.PrivateKey
+}
+
+// NewServiceAccountKey generates a 2048 bit RSA key of the service account with a unique key id.
+func NewServiceAccountKey(serviceAccountID string) (*ServiceAccountKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceAccountKey{
+		ID:               fmt.Sprintf("ajetestkey%010d", atomic.AddInt64(&keyCounter, 1)),
+		ServiceAccountID: serviceAccountID,
+		PrivateKey:       key,
+	}, nil
+}
+
+// PrivateKeyPEM returns the private key in PKCS#8 PEM as in the authorized key file.
+func (k *ServiceAccountKey) PrivateKeyPEM() string {
+	private, _ := x509.MarshalPKCS8PrivateKey(k.PrivateKey)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private}))
+}
+
+// JSON returns the key in the format of authorized key file created by `yc iam key create`,
+// suitable for WithServiceKey.
+func (k *ServiceAccountKey) JSON() string {
+	public, _ := x509.MarshalPKIXPublicKey(&k.PrivateKey.PublicKey)
+	data, _ := json.MarshalIndent(struct {
+		ID               string `json:"id"`
+		ServiceAccountID string `json:"service_account_id"` //nolint:tagliatelle // Yandex Cloud SA key JSON format.
+		KeyAlgorithm     string `json:"key_algorithm"`      //nolint:tagliatelle // Yandex Cloud SA key JSON format.
+		PublicKey        string `json:"public_key"`         //nolint:tagliatelle // Yandex Cloud SA key JSON format.
+		PrivateKey       string `json:"private_key"`        //nolint:tagliatelle // Yandex Cloud SA key JSON format.
+	}{
+		ID:               k.ID,
+		ServiceAccountID: k.ServiceAccountID,
+		KeyAlgorithm:     "RSA_2048",
+		PublicKey:        string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})),
+		PrivateKey:       k.PrivateKeyPEM(),
+	}, "", "  ")
+
+	return string(data)
+}
@@ -0,0 +1,212 @@
+package ydbyctest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Paths of the metadata service.
+const (
+	MetadataInstancePath = "/computeMetadata/v1/instance/"
+	MetadataTokenPath    = MetadataInstancePath + "service-accounts/default/token"
+)
+
+// DefaultMetadataTokenLifetime is the lifetime of tokens issued by MetadataService.
+const DefaultMetadataTokenLifetime = time.Hour
+
+// TokenSource returns an iam token and its expiration.
+type TokenSource func(ctx context.Context) (token string, expiresAt time.Time, err error)
+
+// MetadataService is a fake of the Compute metadata service. It serves the token of the default
+// service account and instance attributes, requests without `Metadata-Flavor: Google` header are
+// rejected as by the real service.
+//
+// MetadataService is a http.Handler, use it with httptest.NewServer and
+// yc.WithMetadataCredentialsURL(server.URL + MetadataTokenPath).
+type MetadataService struct {
+	source   TokenSource // nil if tokens are issued by the service
+	lifetime time.Duration
+	now      func() time.Time
+
+	mu         sync.Mutex
+	attributes map[string]string
+	token      string
+	expiresAt  time.Time
+	issued     int
+	statuses   []int // returned by the next token requests
+	requests   int
+}
+
+type MetadataServiceOption func(*MetadataService)
+
+// WithMetadataTokenSource makes the service return tokens of source instead of issuing fake ones.
+func WithMetadataTokenSource(source TokenSource) MetadataServiceOption {
+	return func(s *MetadataService) {
+		s.source = source
+	}
+}
+
+// WithMetadataTokenLifetime set the lifetime of fake tokens, DefaultMetadataTokenLifetime by default.
+func WithMetadataTokenLifetime(lifetime time.Duration) MetadataServiceOption {
+	return func(s *MetadataService) {
+		s.lifetime = lifetime
+	}
+}
+
+// WithMetadataNow set the source of the current time used for expires_in.
+func WithMetadataNow(now func() time.Time) MetadataServiceOption {
+	return func(s *MetadataService) {
+		s.now = now
+	}
+}
+
+// WithMetadataAttribute set the value of the instance attribute, e.g. "id", "hostname" or
+// "attributes/ssh-keys". Path is relative to MetadataInstancePath.
+func WithMetadataAttribute(path, value string) MetadataServiceOption {
+	return func(s *MetadataService) {
+		s.attributes[path] = value
+	}
+}
+
+func NewMetadataService(opts ...MetadataServiceOption) *MetadataService {
+	s := &MetadataService{
+		lifetime: DefaultMetadataTokenLifetime,
+		now:      time.Now,
+		attributes: map[string]string{
+			"id":       "fhmfakeinstance",
+			"name":     "fake-instance",
+			"hostname": "fake-instance.ru-central1.internal",
+			"zone":     "projects/fake/zones/ru-central1-a",
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Token returns the fake token served now, a new token is issued when the previous one expires.
+func (s *MetadataService) Token() (token string, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := s.now(); s.token == "" || !now.Before(s.expiresAt) {
+		s.issued++
+		s.token = fmt.Sprintf("t1.fake-metadata.%d", s.issued)
+		s.expiresAt = now.Add(s.lifetime)
+	}
+
+	return s.token, s.expiresAt
+}
+
+// Expire makes the service issue a new token on the next request.
+func (s *MetadataService) Expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.token = ""
+}
+
+// FailNext makes the next len(statuses) token requests fail with HTTP statuses in order.
+func (s *MetadataService) FailNext(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statuses = append(s.statuses, statuses...)
+}
+
+// TokenRequests returns the number of received token requests.
+func (s *MetadataService) TokenRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+func (s *MetadataService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Metadata-Flavor") != "Google" {
+		http.Error(w, "Missing required header: Metadata-Flavor: Google", http.StatusForbidden)
+
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+	switch {
+	case r.URL.Path == MetadataTokenPath:
+		s.serveToken(w, r)
+	case strings.HasPrefix(r.URL.Path, MetadataInstancePath):
+		s.mu.Lock()
+		value, ok := s.attributes[strings.TrimPrefix(r.URL.Path, MetadataInstancePath)]
+		s.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+
+			return
+		}
+		w.Header().Set("Metadata-Flavor", "Google")
+		w.Header().Set("Content-Type", "application/text")
+		_, _ = w.Write([]byte(value))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *MetadataService) serveToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	status := http.StatusOK
+	if len(s.statuses) > 0 {
+		status = s.statuses[0]
+		s.statuses = s.statuses[1:]
+	}
+	s.mu.Unlock()
+	if status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+
+		return
+	}
+
+	var (
+		token     string
+		expiresAt time.Time
+	)
+	if s.source != nil {
+		var err error
+		token, expiresAt, err = s.source(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+	} else {
+		token, expiresAt = s.Token()
+	}
+
+	writeMetadataToken(w, token, expiresAt.Sub(s.now()))
+}
+
+// metadataToken is the response of the token endpoint of the metadata service.
+type metadataToken struct {
+	AccessToken string `json:"access_token"` //nolint:tagliatelle // metadata service format.
+	ExpiresIn   int64  `json:"expires_in"`   //nolint:tagliatelle // metadata service format.
+	TokenType   string `json:"token_type"`   //nolint:tagliatelle // metadata service format.
+}
+
+func writeMetadataToken(w http.ResponseWriter, token string, lifetime time.Duration) {
+	w.Header().Set("Metadata-Flavor", "Google")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(metadataToken{
+		AccessToken: token,
+		ExpiresIn:   int64(lifetime / time.Second),
+		TokenType:   "Bearer",
+	})
+}
@@ -0,0 +1,74 @@
+package ydbyctest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	yc "github.com/ydb-platform/ydb-go-yc"
+)
+
+func TestMetadataService(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	metadata := NewMetadataService(
+		WithMetadataNow(func() time.Time { return now }),
+		WithMetadataAttribute("attributes/role", "test"),
+	)
+	srv := httptest.NewServer(metadata)
+	defer srv.Close()
+
+	get := func(path string, header bool) (int, string) {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL+path, nil)
+		require.NoError(t, err)
+		if header {
+			req.Header.Set("Metadata-Flavor", "Google")
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		return res.StatusCode, string(body)
+	}
+
+	status, _ := get(MetadataTokenPath, false)
+	require.Equal(t, http.StatusForbidden, status)
+
+	status, body := get(MetadataTokenPath, true)
+	require.Equal(t, http.StatusOK, status)
+	require.JSONEq(t, `{"access_token":"t1.fake-metadata.1","expires_in":3600,"token_type":"Bearer"}`, body)
+
+	now = now.Add(10 * time.Minute)
+	_, body = get(MetadataTokenPath, true)
+	require.JSONEq(t, `{"access_token":"t1.fake-metadata.1","expires_in":3000,"token_type":"Bearer"}`, body)
+
+	now = now.Add(time.Hour)
+	_, body = get(MetadataTokenPath, true)
+	require.JSONEq(t, `{"access_token":"t1.fake-metadata.2","expires_in":3600,"token_type":"Bearer"}`, body)
+
+	status, body = get(MetadataInstancePath+"attributes/role", true)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "test", body)
+	status, _ = get(MetadataInstancePath+"attributes/unknown", true)
+	require.Equal(t, http.StatusNotFound, status)
+
+	metadata.FailNext(http.StatusInternalServerError)
+	status, _ = get(MetadataTokenPath, true)
+	require.Equal(t, http.StatusInternalServerError, status)
+	require.Equal(t, 4, metadata.TokenRequests())
+}
+
+func TestMetadataServiceCredentials(t *testing.T) {
+	srv := httptest.NewServer(NewMetadataService())
+	defer srv.Close()
+
+	token, err := yc.NewInstanceServiceAccountURL(srv.URL + MetadataTokenPath).Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "t1.fake-metadata.1", token)
+}
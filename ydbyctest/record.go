@@ -0,0 +1,468 @@
+package ydbyctest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ydb-platform/ydb-go-yc/internal/auth"
+)
+
+// ErrUnexpectedExchange is returned by Replayer when the request does not match the recording.
+var ErrUnexpectedExchange = errors.New("ydbyctest: unexpected token exchange")
+
+// Kinds of recorded exchanges.
+const (
+	// ExchangeJWT is IamTokenService.Create with the jwt signed by a service account key.
+	ExchangeJWT = "jwt"
+	// ExchangeOAuth is IamTokenService.Create with the OAuth token of Yandex account (WithOAuthToken).
+	ExchangeOAuth = "oauth"
+	// ExchangeServiceAccount is IamTokenService.CreateForServiceAccount (impersonation).
+	ExchangeServiceAccount = "service_account"
+	// ExchangeMetadata is a token request to the metadata service (Recorder.Metadata).
+	ExchangeMetadata = "metadata"
+)
+
+// Exchange is a recorded call of IamTokenService or token request to the metadata service. Secrets
+// (the jwt, the OAuth token and iam tokens) are replaced with deterministic placeholders. The request
+// is identified by its kind and by the jwt claims or the impersonated service account.
+type Exchange struct {
+	// Kind is one of Exchange* constants, empty for ExchangeJWT.
+	Kind string `json:"kind,omitempty"`
+
+	JWT      string `json:"jwt,omitempty"`
+	KeyID    string `json:"key_id,omitempty"` //nolint:tagliatelle // golden file format.
+	Issuer   string `json:"issuer,omitempty"`
+	Audience string `json:"audience,omitempty"`
+
+	// ServiceAccountID is the impersonated service account, IAMToken is the placeholder of the token
+	// which authorized the impersonation (the placeholder of a token issued by an earlier exchange, if any).
+	ServiceAccountID string `json:"service_account_id,omitempty"` //nolint:tagliatelle // golden file format.
+	IAMToken         string `json:"iam_token,omitempty"`          //nolint:tagliatelle // golden file format.
+
+	Token string `json:"token,omitempty"`
+	// Lifetime is the time from the exchange to the token expiration.
+	Lifetime string `json:"lifetime,omitempty"`
+
+	// Code is the grpc code of a failed IAM exchange, Status is the HTTP status of a failed
+	// metadata request.
+	Code    string `json:"code,omitempty"`
+	Status  int    `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type recording struct {
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+func newJWTExchange(signed string) Exchange {
+	var (
+		e      Exchange
+		claims jwt.RegisteredClaims
+	)
+	if t, _, err := jwt.NewParser().ParseUnverified(signed, &claims); err == nil {
+		e.KeyID, _ = t.Header["kid"].(string)
+		e.Issuer = claims.Issuer
+		if len(claims.Audience) > 0 {
+			e.Audience = claims.Audience[0]
+		}
+	}
+
+	return e
+}
+
+func (e Exchange) kind() string {
+	if e.Kind == "" {
+		return ExchangeJWT
+	}
+
+	return e.Kind
+}
+
+func (e Exchange) matches(x Exchange) bool {
+	if e.kind() != x.kind() {
+		return false
+	}
+	switch e.kind() {
+	case ExchangeJWT:
+		return e.KeyID == x.KeyID && e.Issuer == x.Issuer && e.Audience == x.Audience
+	case ExchangeServiceAccount:
+		return e.ServiceAccountID == x.ServiceAccountID && e.IAMToken == x.IAMToken
+	default:
+		return true
+	}
+}
+
+func (e Exchange) String() string {
+	switch e.kind() {
+	case ExchangeJWT:
+		return fmt.Sprintf("kid=%s iss=%s aud=%s", e.KeyID, e.Issuer, e.Audience)
+	case ExchangeServiceAccount:
+		return fmt.Sprintf("service account %s by %s", e.ServiceAccountID, e.IAMToken)
+	default:
+		return e.kind()
+	}
+}
+
+// oauthExchanger and impersonationExchanger are the exchanges of OAuth tokens and of impersonation
+// supported by the client transport.
+type (
+	oauthExchanger interface {
+		CreateTokenFromOAuth(ctx context.Context, oauthToken string) (string, time.Time, error)
+	}
+	impersonationExchanger = auth.ImpersonationExchanger
+)
+
+// Recorder is a TokenExchanger which passes exchanges to the next exchanger and writes them to
+// the golden file after each exchange. OAuth and impersonation exchanges are recorded if next
+// supports them. Token requests of metadata credentials are recorded by the handler of Metadata.
+type Recorder struct {
+	next auth.TokenExchanger
+	path string
+	now  func() time.Time
+
+	mu        sync.Mutex
+	recording recording
+	tokens    map[string]string // placeholders of issued tokens
+}
+
+// NewRecorder records exchanges of next to the file at path, the file is overwritten. Next may be
+// nil if only metadata requests are recorded.
+func NewRecorder(next auth.TokenExchanger, path string) *Recorder {
+	return &Recorder{
+		next:   next,
+		path:   path,
+		now:    time.Now,
+		tokens: make(map[string]string),
+	}
+}
+
+func (r *Recorder) CreateToken(ctx context.Context, signed string) (string, time.Time, error) {
+	if r.next == nil {
+		return "", time.Time{}, errors.New("ydbyctest: recorder has no exchanger")
+	}
+
+	return r.record(newJWTExchange(signed), func() (string, time.Time, error) {
+		return r.next.CreateToken(ctx, signed)
+	})
+}
+
+func (r *Recorder) CreateTokenFromOAuth(ctx context.Context, oauthToken string) (string, time.Time, error) {
+	next, ok := r.next.(oauthExchanger)
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("ydbyctest: recorded exchanger does not support OAuth tokens")
+	}
+
+	return r.record(Exchange{Kind: ExchangeOAuth}, func() (string, time.Time, error) {
+		return next.CreateTokenFromOAuth(ctx, oauthToken)
+	})
+}
+
+func (r *Recorder) CreateTokenForServiceAccount(
+	ctx context.Context, iamToken, serviceAccountID string,
+) (string, time.Time, error) {
+	next, ok := r.next.(impersonationExchanger)
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("ydbyctest: recorded exchanger does not support impersonation")
+	}
+	r.mu.Lock()
+	placeholder, ok := r.tokens[iamToken]
+	r.mu.Unlock()
+	if !ok {
+		placeholder = "<iam-token>"
+	}
+
+	return r.record(Exchange{
+		Kind:             ExchangeServiceAccount,
+		ServiceAccountID: serviceAccountID,
+		IAMToken:         placeholder,
+	}, func() (string, time.Time, error) {
+		return next.CreateTokenForServiceAccount(ctx, iamToken, serviceAccountID)
+	})
+}
+
+// Metadata returns the handler of MetadataTokenPath which passes token requests to tokenURL of
+// the real metadata service and records them. Pass the URL of the handler's server with
+// MetadataTokenPath to metadata credentials.
+func (r *Recorder) Metadata(tokenURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !checkMetadataRequest(w, req) {
+			return
+		}
+		e := Exchange{Kind: ExchangeMetadata}
+		token, lifetime, code, err := fetchMetadataToken(req, tokenURL)
+		if err != nil {
+			e.Status, e.Message = code, err.Error()
+		}
+		if saveErr := r.add(e, token, lifetime); saveErr != nil {
+			http.Error(w, saveErr.Error(), http.StatusInternalServerError)
+
+			return
+		}
+		if err != nil {
+			http.Error(w, e.Message, e.Status)
+
+			return
+		}
+		writeMetadataToken(w, token, lifetime)
+	})
+}
+
+// fetchMetadataToken requests the token from the real metadata service. The status is the one to
+// respond with on error.
+func fetchMetadataToken(req *http.Request, tokenURL string) (string, time.Duration, int, error) {
+	out, err := http.NewRequestWithContext(req.Context(), http.MethodGet, tokenURL, nil)
+	if err != nil {
+		return "", 0, http.StatusInternalServerError, err
+	}
+	out.Header.Set("Metadata-Flavor", "Google")
+	resp, err := http.DefaultClient.Do(out)
+	if err != nil {
+		return "", 0, http.StatusBadGateway, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, http.StatusBadGateway, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, resp.StatusCode, errors.New(strings.TrimSpace(string(body)))
+	}
+	var token metadataToken
+	if err = json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return "", 0, http.StatusBadGateway, fmt.Errorf("invalid token response of metadata service: %s", body)
+	}
+
+	return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, http.StatusOK, nil
+}
+
+// checkMetadataRequest responds with the error of the metadata service if the request is not
+// a token request.
+func checkMetadataRequest(w http.ResponseWriter, req *http.Request) bool {
+	switch {
+	case req.Header.Get("Metadata-Flavor") != "Google":
+		http.Error(w, "Missing required header: Metadata-Flavor: Google", http.StatusForbidden)
+	case req.Method != http.MethodGet:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	case req.URL.Path != MetadataTokenPath:
+		http.NotFound(w, req)
+	default:
+		return true
+	}
+
+	return false
+}
+
+// record makes the exchange and appends it to the recording.
+func (r *Recorder) record(e Exchange, exchange func() (string, time.Time, error)) (string, time.Time, error) {
+	now := r.now()
+	token, expires, err := exchange()
+	if err != nil {
+		s := status.Convert(err)
+		e.Code, e.Message = s.Code().String(), s.Message()
+	}
+	if saveErr := r.add(e, token, expires.Sub(now)); saveErr != nil {
+		return "", time.Time{}, saveErr
+	}
+
+	return token, expires, err
+}
+
+// add appends the exchange to the recording, token with its lifetime is replaced with a placeholder
+// unless the exchange failed.
+func (r *Recorder) add(e Exchange, token string, lifetime time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(r.recording.Exchanges) + 1
+	if e.kind() == ExchangeJWT {
+		e.JWT = fmt.Sprintf("<jwt-%d>", n)
+	}
+	if e.Code == "" && e.Status == 0 {
+		e.Token = fmt.Sprintf("<token-%d>", n)
+		e.Lifetime = lifetime.Round(time.Second).String()
+		r.tokens[token] = e.Token
+	}
+	r.recording.Exchanges = append(r.recording.Exchanges, e)
+	if err := r.save(); err != nil {
+		return fmt.Errorf("ydbyctest: cannot save recording: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Recorder) save() error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false) // keep placeholders like <token-1> readable
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r.recording); err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.path, buf.Bytes(), 0o600)
+}
+
+// Replayer is a TokenExchanger which serves exchanges recorded by Recorder in order. Tokens are
+// the placeholders of the recording, expiration is the recorded lifetime from now.
+// Metadata token requests are served by the handler of Metadata.
+type Replayer struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	exchanges []Exchange
+	next      int
+}
+
+type ReplayerOption func(*Replayer)
+
+// WithReplayNow set the source of the current time used for expiration of tokens.
+func WithReplayNow(now func() time.Time) ReplayerOption {
+	return func(r *Replayer) {
+		r.now = now
+	}
+}
+
+// NewReplayer loads the recording from the file at path.
+func NewReplayer(path string, opts ...ReplayerOption) (*Replayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec recording
+	if err = json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("ydbyctest: invalid recording '%s': %w", path, err)
+	}
+	r := &Replayer{
+		now:       time.Now,
+		exchanges: rec.Exchanges,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
+}
+
+func (r *Replayer) CreateToken(ctx context.Context, signed string) (string, time.Time, error) {
+	return r.replay(newJWTExchange(signed))
+}
+
+func (r *Replayer) CreateTokenFromOAuth(ctx context.Context, oauthToken string) (string, time.Time, error) {
+	return r.replay(Exchange{Kind: ExchangeOAuth})
+}
+
+// CreateTokenForServiceAccount replays impersonation, iamToken is expected to be the placeholder
+// of the token issued by the recorded exchange which authorized it.
+func (r *Replayer) CreateTokenForServiceAccount(
+	ctx context.Context, iamToken, serviceAccountID string,
+) (string, time.Time, error) {
+	return r.replay(Exchange{Kind: ExchangeServiceAccount, ServiceAccountID: serviceAccountID, IAMToken: iamToken})
+}
+
+// Metadata returns the handler of MetadataTokenPath which serves token requests recorded by
+// Recorder.Metadata.
+func (r *Replayer) Metadata() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !checkMetadataRequest(w, req) {
+			return
+		}
+		e, err := r.take(Exchange{Kind: ExchangeMetadata})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+		if e.Status != 0 {
+			http.Error(w, e.Message, e.Status)
+
+			return
+		}
+		lifetime, err := time.ParseDuration(e.Lifetime)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("ydbyctest: invalid lifetime '%s' of exchange %s", e.Lifetime, e),
+				http.StatusInternalServerError,
+			)
+
+			return
+		}
+		writeMetadataToken(w, e.Token, lifetime)
+	})
+}
+
+// replay returns the result of the next recorded exchange if it matches got.
+func (r *Replayer) replay(got Exchange) (string, time.Time, error) {
+	e, err := r.take(got)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if e.Code != "" {
+		code, ok := parseCode(e.Code)
+		if !ok {
+			return "", time.Time{}, fmt.Errorf("ydbyctest: invalid code '%s' of exchange %s", e.Code, e)
+		}
+
+		return "", time.Time{}, status.Error(code, e.Message)
+	}
+	lifetime, err := time.ParseDuration(e.Lifetime)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("ydbyctest: invalid lifetime '%s' of exchange %s", e.Lifetime, e)
+	}
+
+	return e.Token, r.now().Add(lifetime), nil
+}
+
+// take returns the next recorded exchange if it matches got.
+func (r *Replayer) take(got Exchange) (Exchange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next >= len(r.exchanges) {
+		return Exchange{}, fmt.Errorf("%w: no more exchanges recorded, got %s", ErrUnexpectedExchange, got)
+	}
+	e := r.exchanges[r.next]
+	if got.kind() == ExchangeServiceAccount && e.kind() == ExchangeServiceAccount &&
+		e.IAMToken == "<iam-token>" {
+		// The authorizing token was not issued by the recording, so it is not compared.
+		got.IAMToken = e.IAMToken
+	}
+	if !e.matches(got) {
+		return Exchange{}, fmt.Errorf("%w: exchange %d recorded for %s, got %s",
+			ErrUnexpectedExchange, r.next+1, e, got,
+		)
+	}
+	r.next++
+
+	return e, nil
+}
+
+// Remaining returns the number of recorded exchanges which were not replayed yet.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.exchanges) - r.next
+}
+
+// parseCode parses grpc code from its String form.
+func parseCode(s string) (codes.Code, bool) {
+	for code := codes.OK; code <= codes.Unauthenticated; code++ {
+		if code.String() == s {
+			return code, true
+		}
+	}
+
+	return codes.Unknown, false
+}
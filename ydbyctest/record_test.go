@@ -0,0 +1,186 @@
+package ydbyctest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	yc "github.com/ydb-platform/ydb-go-yc"
+	"github.com/ydb-platform/ydb-go-yc/internal/auth"
+)
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "iam.golden.json")
+	key, err := NewServiceAccountKey("sa-1")
+	require.NoError(t, err)
+
+	calls := 0
+	recorder := NewRecorder(auth.TokenExchangerFunc(func(ctx context.Context, jwt string) (string, time.Time, error) {
+		calls++
+		if calls == 1 {
+			return "", time.Time{}, status.Error(codes.Unavailable, "try again")
+		}
+
+		return "t1.real-secret-token", time.Now().Add(12 * time.Hour), nil
+	}), path)
+	creds, err := yc.NewClient(yc.WithServiceKey(key.JSON()), yc.WithTransport(recorder), yc.WithRetry(2, time.Millisecond))
+	require.NoError(t, err)
+	token, err := creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "t1.real-secret-token", token)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "t1.real-secret-token")
+	require.NotContains(t, string(data), "eyJ")
+	require.Contains(t, string(data), `"lifetime": "12h0m0s"`)
+
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	replayer, err := NewReplayer(path, WithReplayNow(func() time.Time { return now }))
+	require.NoError(t, err)
+	creds, err = yc.NewClient(yc.WithServiceKey(key.JSON()), yc.WithTransport(replayer), yc.WithRetry(2, time.Millisecond))
+	require.NoError(t, err)
+	token, err = creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "<token-2>", token)
+	require.Equal(t, 0, replayer.Remaining())
+
+	_, _, err = replayer.CreateToken(ctx, "jwt")
+	require.ErrorIs(t, err, ErrUnexpectedExchange)
+
+	t.Run("Mismatch", func(t *testing.T) {
+		other, err := NewServiceAccountKey("sa-2")
+		require.NoError(t, err)
+		replayer, err := NewReplayer(path)
+		require.NoError(t, err)
+		creds, err := yc.NewClient(yc.WithServiceKey(other.JSON()), yc.WithTransport(replayer))
+		require.NoError(t, err)
+		_, err = creds.Token(ctx)
+		require.ErrorIs(t, err, ErrUnexpectedExchange)
+		require.True(t, strings.Contains(err.Error(), "iss=sa-2"), err)
+	})
+}
+
+// stubExchanger issues tokens for OAuth tokens and impersonation.
+type stubExchanger struct {
+	auth.TokenExchangerFunc
+}
+
+func (stubExchanger) CreateTokenFromOAuth(ctx context.Context, oauthToken string) (string, time.Time, error) {
+	return "real-oauth-iam-token", time.Now().Add(time.Hour), nil
+}
+
+func (stubExchanger) CreateTokenForServiceAccount(
+	ctx context.Context, iamToken, serviceAccountID string,
+) (string, time.Time, error) {
+	return "real-" + serviceAccountID + "-token", time.Now().Add(time.Hour), nil
+}
+
+func TestRecordReplayOAuthImpersonation(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "iam.golden.json")
+
+	recorder := NewRecorder(stubExchanger{}, path)
+	creds, err := yc.NewClient(yc.WithOAuthToken("y0_secret"), yc.WithTransport(recorder),
+		yc.WithImpersonatedServiceAccount("sa-1"),
+	)
+	require.NoError(t, err)
+	token, err := creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "real-sa-1-token", token)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "y0_secret")
+	require.NotContains(t, string(data), "real-")
+	require.Contains(t, string(data), `"kind": "oauth"`)
+	require.Contains(t, string(data), `"iam_token": "<token-1>"`)
+
+	replayer, err := NewReplayer(path)
+	require.NoError(t, err)
+	creds, err = yc.NewClient(yc.WithOAuthToken("y0_other"), yc.WithTransport(replayer),
+		yc.WithImpersonatedServiceAccount("sa-1"),
+	)
+	require.NoError(t, err)
+	token, err = creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "<token-2>", token)
+	require.Equal(t, 0, replayer.Remaining())
+
+	replayer, err = NewReplayer(path)
+	require.NoError(t, err)
+	creds, err = yc.NewClient(yc.WithOAuthToken("y0_secret"), yc.WithTransport(replayer),
+		yc.WithImpersonatedServiceAccount("sa-2"),
+	)
+	require.NoError(t, err)
+	_, err = creds.Token(ctx)
+	require.ErrorIs(t, err, ErrUnexpectedExchange)
+	require.Contains(t, err.Error(), "service account sa-2")
+
+	// Exchangers which do not support a flow are reported.
+	_, _, err = NewRecorder(auth.TokenExchangerFunc(nil), path).CreateTokenFromOAuth(ctx, "y0_secret")
+	require.Error(t, err)
+}
+
+func TestRecordReplayMetadata(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metadata.golden.json")
+	metadata := NewMetadataService()
+	upstream := httptest.NewServer(metadata)
+	defer upstream.Close()
+	get := func(url string, flavor bool) int {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		require.NoError(t, err)
+		if flavor {
+			req.Header.Set("Metadata-Flavor", "Google")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		return resp.StatusCode
+	}
+
+	recorder := NewRecorder(nil, path)
+	srv := httptest.NewServer(recorder.Metadata(upstream.URL + MetadataTokenPath))
+	defer srv.Close()
+	require.Equal(t, http.StatusForbidden, get(srv.URL+MetadataTokenPath, false))
+	metadata.FailNext(http.StatusServiceUnavailable)
+	require.Equal(t, http.StatusServiceUnavailable, get(srv.URL+MetadataTokenPath, true))
+	creds := yc.NewInstanceServiceAccountURL(srv.URL + MetadataTokenPath)
+	t.Cleanup(creds.Stop)
+	token, err := creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "t1.fake-metadata.1", token)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"kind": "metadata"`)
+	require.Contains(t, string(data), `"status": 503`)
+	require.Contains(t, string(data), "<token-2>")
+	require.NotContains(t, string(data), "t1.fake-metadata")
+
+	replayer, err := NewReplayer(path)
+	require.NoError(t, err)
+	srv = httptest.NewServer(replayer.Metadata())
+	defer srv.Close()
+	require.Equal(t, http.StatusForbidden, get(srv.URL+MetadataTokenPath, false))
+	require.Equal(t, http.StatusServiceUnavailable, get(srv.URL+MetadataTokenPath, true))
+	creds = yc.NewInstanceServiceAccountURL(srv.URL + MetadataTokenPath)
+	t.Cleanup(creds.Stop)
+	token, err = creds.Token(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "<token-2>", token)
+	require.Equal(t, 0, replayer.Remaining())
+	require.Equal(t, http.StatusInternalServerError, get(srv.URL+MetadataTokenPath, true))
+}
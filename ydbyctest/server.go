@@ -0,0 +1,112 @@
+package ydbyctest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/ydb-platform/ydb-go-yc/internal/auth"
+)
+
+// Server is a grpc server on localhost with a self-signed TLS certificate.
+type Server struct {
+	// Endpoint is the address of the server in host:port form.
+	Endpoint string
+	// CertPool contains the certificate of the server.
+	CertPool *x509.CertPool
+
+	cert  []byte // DER of the certificate
+	srv   *grpc.Server
+	serve chan error
+}
+
+// NewServer starts a server with the services registered, e.g. IAMTokenService.Register.
+func NewServer(register ...func(*grpc.Server)) (*Server, error) {
+	cert, certPool, err := selfSignedCertificate()
+	if err != nil {
+		return nil, err
+	}
+	ln, err := (&net.ListenConfig{}).Listen(context.Background(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		Endpoint: ln.Addr().String(),
+		CertPool: certPool,
+		cert:     cert.Leaf.Raw,
+		srv:      grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&cert))),
+		serve:    make(chan error, 1),
+	}
+	for _, r := range register {
+		r(s.srv)
+	}
+	go func() { s.serve <- s.srv.Serve(ln) }()
+
+	return s, nil
+}
+
+// ClientOptions returns options which connect iam client to the server.
+func (s *Server) ClientOptions() []auth.ClientOption {
+	return []auth.ClientOption{
+		auth.WithEndpoint(s.Endpoint),
+		auth.WithCertPool(s.CertPool),
+	}
+}
+
+// CertificatePEM returns the certificate of the server in PEM, e.g. to write a CA file.
+func (s *Server) CertificatePEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.cert})
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	s.srv.Stop()
+
+	return <-s.serve
+}
+
+func selfSignedCertificate() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ydbyctest"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	certPool := x509.NewCertPool()
+	certPool.AddCert(leaf)
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, certPool, nil
+}
@@ -0,0 +1,400 @@
+package ydbyctest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+
+	"github.com/ydb-platform/ydb-go-yc/internal/auth"
+)
+
+// ErrInjected is returned by Backend after SetError(ErrInjected).
+var ErrInjected = errors.New("ydbyctest: injected error")
+
+// Backend is the source of tokens for credentials under RunCredentialsSuite. Credentials are wired
+// to it with TokenExchanger, MetadataURL or Token, and use it as the clock where possible.
+type Backend struct {
+	lifetime time.Duration
+	release  func()
+	done     chan struct{}
+
+	mu      sync.Mutex
+	now     time.Time
+	issued  int
+	err     error
+	latency time.Duration
+	secrets []string
+}
+
+// NewBackend creates a backend issuing tokens with one hour lifetime.
+func NewBackend(t testing.TB) *Backend {
+	b := &Backend{
+		lifetime: time.Hour,
+		now:      time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		done:     make(chan struct{}),
+	}
+	var once sync.Once
+	b.release = func() {
+		once.Do(func() { close(b.done) })
+	}
+	t.Cleanup(b.release)
+
+	return b
+}
+
+// Now implements yc.Clock.
+func (b *Backend) Now() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.now
+}
+
+// Advance moves the clock of the backend forward.
+func (b *Backend) Advance(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.now = b.now.Add(d)
+}
+
+// Lifetime returns the lifetime of issued tokens.
+func (b *Backend) Lifetime() time.Duration {
+	return b.lifetime
+}
+
+// SetError makes token requests fail with err until it is reset with nil.
+func (b *Backend) SetError(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.err = err
+}
+
+// SetLatency delays token requests, the delay is interrupted if the request is canceled.
+func (b *Backend) SetLatency(latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.latency = latency
+}
+
+// AddSecret registers a value which must not appear in String() and errors of credentials, e.g.
+// a private key. Lines of a PEM body are registered separately, so a partial leak is detected too.
+func (b *Backend) AddSecret(secret string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.secrets = append(b.secrets, secret)
+	if !strings.Contains(secret, "-----BEGIN ") {
+		return
+	}
+	for _, line := range strings.Split(secret, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "-----") {
+			b.secrets = append(b.secrets, line)
+		}
+	}
+}
+
+// leaks returns the registered secret which s contains, if any.
+func (b *Backend) leaks(s string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, secret := range b.secrets {
+		if strings.Contains(s, secret) {
+			return secret, true
+		}
+	}
+
+	return "", false
+}
+
+// Issued returns the number of issued tokens.
+func (b *Backend) Issued() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.issued
+}
+
+// Token issues a new token, it is a TokenSource.
+func (b *Backend) Token(ctx context.Context) (string, time.Time, error) {
+	b.mu.Lock()
+	latency := b.latency
+	b.mu.Unlock()
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return "", time.Time{}, ctx.Err()
+		case <-b.done:
+			return "", time.Time{}, errors.New("ydbyctest: backend is closed")
+		case <-timer.C:
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return "", time.Time{}, b.err
+	}
+	b.issued++
+	token := fmt.Sprintf("t1.suite-secret-token.%d", b.issued)
+	b.secrets = append(b.secrets, token)
+
+	return token, b.now.Add(b.lifetime), nil
+}
+
+// TokenExchanger returns exchanger for yc.WithTransport which ignores the jwt, the OAuth token and
+// the authorizing token of impersonation and issues tokens.
+func (b *Backend) TokenExchanger() auth.TokenExchanger {
+	return backendExchanger{b}
+}
+
+type backendExchanger struct {
+	b *Backend
+}
+
+func (e backendExchanger) CreateToken(ctx context.Context, _ string) (string, time.Time, error) {
+	return e.b.Token(ctx)
+}
+
+func (e backendExchanger) CreateTokenFromOAuth(ctx context.Context, _ string) (string, time.Time, error) {
+	return e.b.Token(ctx)
+}
+
+func (e backendExchanger) CreateTokenForServiceAccount(ctx context.Context, _, _ string) (string, time.Time, error) {
+	return e.b.Token(ctx)
+}
+
+// IAMTokenFile writes a token issued by the backend to a file in the format of
+// `yc iam create-token --format json` and returns its path, for static iam token credentials.
+// If the backend fails, the file is empty.
+func (b *Backend) IAMTokenFile(t testing.TB) string {
+	b.mu.Lock()
+	var data []byte
+	if b.err == nil {
+		b.issued++
+		token := fmt.Sprintf("t1.suite-secret-token.%d", b.issued)
+		b.secrets = append(b.secrets, token)
+		data, _ = json.Marshal(map[string]string{
+			"iam_token":  token,
+			"expires_at": b.now.Add(b.lifetime).Format(time.RFC3339),
+		})
+	}
+	b.mu.Unlock()
+
+	path := filepath.Join(t.TempDir(), "iam-token.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// MetadataURL starts MetadataService backed by the backend and returns its token url.
+func (b *Backend) MetadataURL(t testing.TB) string {
+	srv := httptest.NewServer(NewMetadataService(
+		WithMetadataTokenSource(b.Token),
+		WithMetadataNow(b.Now),
+	))
+	t.Cleanup(func() {
+		b.release()
+		srv.Close()
+	})
+
+	return srv.URL + MetadataTokenPath
+}
+
+// CredentialsFactory creates credentials under test wired to the backend. Credentials are created
+// for each check of the suite.
+type CredentialsFactory func(t *testing.T, b *Backend) credentials.Credentials
+
+type suite struct {
+	skipExpiry       bool
+	skipCancellation bool
+	opaqueErrors     bool
+}
+
+type SuiteOption func(*suite)
+
+// WithoutExpiryCheck skips the check of token refresh after expiration, for credentials which do
+// not use Backend as the clock (e.g. refresh in background by real time).
+func WithoutExpiryCheck() SuiteOption {
+	return func(s *suite) {
+		s.skipExpiry = true
+	}
+}
+
+// WithoutCancellationCheck skips the check of context cancellation, for credentials which do not
+// make requests (e.g. a static token read from a file).
+func WithoutCancellationCheck() SuiteOption {
+	return func(s *suite) {
+		s.skipCancellation = true
+	}
+}
+
+// WithOpaqueErrors allows errors of credentials not to wrap the error of the backend, e.g. when
+// the token is received over http.
+func WithOpaqueErrors() SuiteOption {
+	return func(s *suite) {
+		s.opaqueErrors = true
+	}
+}
+
+// RunCredentialsSuite checks that credentials made by factory behave as credentials of ydb-go-yc:
+// concurrent Token calls share one token, the token is cached until expiration and refreshed after,
+// Token respects context cancellation, errors of the backend are returned wrapped, and neither
+// String() nor errors reveal tokens and secrets.
+func RunCredentialsSuite(t *testing.T, factory CredentialsFactory, opts ...SuiteOption) {
+	var s suite
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	t.Run("ConcurrentToken", func(t *testing.T) {
+		b := NewBackend(t)
+		creds := factory(t, b)
+		const n = 16
+		var (
+			wg     sync.WaitGroup
+			tokens = make([]string, n)
+			errs   = make([]error, n)
+		)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				tokens[i], errs[i] = creds.Token(context.Background())
+			}(i)
+		}
+		wg.Wait()
+		for i := range tokens {
+			if errs[i] != nil {
+				t.Fatalf("Token failed: %v", errs[i])
+			}
+			if tokens[i] == "" || tokens[i] != tokens[0] {
+				t.Fatalf("concurrent Token calls returned different tokens: %q and %q", tokens[0], tokens[i])
+			}
+		}
+		if issued := b.Issued(); issued != 1 {
+			t.Errorf("concurrent Token calls issued %d tokens, want 1", issued)
+		}
+	})
+
+	t.Run("Caching", func(t *testing.T) {
+		b := NewBackend(t)
+		creds := factory(t, b)
+		first := mustToken(t, creds)
+		b.Advance(b.Lifetime() / 4)
+		if token := mustToken(t, creds); token != first {
+			t.Errorf("token is not cached: got %q after %q", token, first)
+		}
+		if issued := b.Issued(); issued != 1 {
+			t.Errorf("issued %d tokens, want 1", issued)
+		}
+	})
+
+	t.Run("ExpiryRefresh", func(t *testing.T) {
+		if s.skipExpiry {
+			t.Skip("expiry check is disabled")
+		}
+		b := NewBackend(t)
+		creds := factory(t, b)
+		first := mustToken(t, creds)
+		b.Advance(b.Lifetime() + time.Minute)
+		if token := mustToken(t, creds); token == first {
+			t.Errorf("expired token %q is returned", token)
+		}
+	})
+
+	t.Run("Cancellation", func(t *testing.T) {
+		if s.skipCancellation {
+			t.Skip("cancellation check is disabled")
+		}
+		b := NewBackend(t)
+		b.SetLatency(time.Hour)
+		creds := factory(t, b)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		token, err := creds.Token(ctx)
+		if err == nil {
+			t.Fatalf("Token returned %q despite canceled context", token)
+		}
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("Token returned %v after the context was canceled", elapsed)
+		}
+	})
+
+	t.Run("ErrorPropagation", func(t *testing.T) {
+		b := NewBackend(t)
+		b.SetError(ErrInjected)
+		creds := factory(t, b)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		token, err := creds.Token(ctx)
+		switch {
+		case err == nil:
+			t.Fatalf("Token returned %q despite backend error", token)
+		case token != "":
+			t.Errorf("Token returned both token %q and error %v", token, err)
+		case !s.opaqueErrors && !errors.Is(err, ErrInjected):
+			t.Errorf("error %v does not wrap the backend error", err)
+		}
+	})
+
+	t.Run("StringRedaction", func(t *testing.T) {
+		b := NewBackend(t)
+		creds := factory(t, b)
+		mustToken(t, creds)
+		stringer, ok := creds.(fmt.Stringer)
+		if !ok {
+			t.Skip("credentials do not implement fmt.Stringer")
+		}
+		s := stringer.String()
+		if secret, ok := b.leaks(s); ok {
+			t.Errorf("String() %q reveals secret %q", s, secret)
+		}
+	})
+
+	t.Run("ErrorRedaction", func(t *testing.T) {
+		b := NewBackend(t)
+		creds := factory(t, b)
+		mustToken(t, creds)
+		b.SetError(ErrInjected)
+		b.Advance(b.Lifetime() + time.Minute)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, err := creds.Token(ctx)
+		if err == nil {
+			t.Skip("credentials do not fail after expiration")
+		}
+		if secret, ok := b.leaks(err.Error()); ok {
+			t.Errorf("error %q reveals secret %q", err, secret)
+		}
+	})
+}
+
+func mustToken(t *testing.T, creds credentials.Credentials) string {
+	t.Helper()
+	token, err := creds.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+
+	return token
+}
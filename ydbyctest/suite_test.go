@@ -0,0 +1,77 @@
+package ydbyctest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ydb-platform/ydb-go-sdk/v3/credentials"
+
+	yc "github.com/ydb-platform/ydb-go-yc"
+)
+
+func newSuiteClient(t *testing.T, b *Backend) credentials.Credentials {
+	key, err := NewServiceAccountKey("sa-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.AddSecret(key.PrivateKeyPEM())
+	creds, err := yc.NewClient(
+		yc.WithServiceKey(key.JSON()),
+		yc.WithTransport(b.TokenExchanger()),
+		yc.WithClock(b),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return creds
+}
+
+func TestClientConformance(t *testing.T) {
+	RunCredentialsSuite(t, newSuiteClient)
+}
+
+func TestFallbackConformance(t *testing.T) {
+	RunCredentialsSuite(t, func(t *testing.T, b *Backend) credentials.Credentials {
+		creds, err := yc.NewClient(
+			yc.WithServiceFile(filepath.Join(t.TempDir(), "missing.json")),
+			yc.WithFallbackCredentials(newSuiteClient(t, b)),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return creds
+	})
+}
+
+func TestImpersonationConformance(t *testing.T) {
+	RunCredentialsSuite(t, func(t *testing.T, b *Backend) credentials.Credentials {
+		b.AddSecret("t1.suite-base-token")
+		creds, err := yc.NewImpersonatedCredentials(
+			credentials.NewAccessTokenCredentials("t1.suite-base-token"), "sa-1",
+			yc.WithTransport(b.TokenExchanger()),
+			yc.WithClock(b),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return creds
+	})
+}
+
+func TestIAMTokenConformance(t *testing.T) {
+	RunCredentialsSuite(t, func(t *testing.T, b *Backend) credentials.Credentials {
+		return yc.NewIAMTokenFileCredentials(b.IAMTokenFile(t), yc.WithIAMTokenClock(b))
+	}, WithoutExpiryCheck(), WithoutCancellationCheck(), WithOpaqueErrors())
+}
+
+func TestMetadataConformance(t *testing.T) {
+	RunCredentialsSuite(t, func(t *testing.T, b *Backend) credentials.Credentials {
+		creds := yc.NewInstanceServiceAccountURL(b.MetadataURL(t))
+		t.Cleanup(creds.Stop)
+
+		return creds
+	}, WithoutExpiryCheck(), WithOpaqueErrors())
+}